@@ -14,6 +14,7 @@ import (
 	"k8s.io/client-go/rest"
 
 	"github.com/jmckind/apache-bench-operator/pkg/apis"
+	"github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
 	"github.com/jmckind/apache-bench-operator/pkg/controller"
 	"github.com/jmckind/apache-bench-operator/version"
 
@@ -38,6 +39,7 @@ var (
 	metricsHost               = "0.0.0.0"
 	metricsPort         int32 = 8383
 	operatorMetricsPort int32 = 8686
+	webhookPort               = 9443
 )
 var log = logf.Log.WithName("cmd")
 
@@ -57,6 +59,13 @@ func main() {
 	// controller-runtime)
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
+	// The webhook server requires a TLS certificate, so the webhooks are only served when enabled.
+	enableWebhooks := pflag.Bool("enable-webhooks", false,
+		"Serve the validating admission webhook for ApacheBench resources. The TLS certificate and key are read from "+
+			"the tls.crt and tls.key files in the directory set by --webhook-cert-dir.")
+	webhookCertDir := pflag.String("webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"The directory that contains the TLS certificate and key for the webhook server.")
+
 	pflag.Parse()
 
 	// Use a zap logr.Logger implementation. If none of the zap
@@ -96,6 +105,8 @@ func main() {
 	options := manager.Options{
 		Namespace:          namespace,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		Port:               webhookPort,
+		CertDir:            *webhookCertDir,
 	}
 
 	// Add support for MultiNamespace set in WATCH_NAMESPACE (e.g ns1,ns2)
@@ -128,6 +139,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Setup all Webhooks
+	if *enableWebhooks {
+		if err := addWebhooks(mgr); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Add the Metrics Service
 	addMetrics(ctx, cfg)
 
@@ -184,6 +203,13 @@ func addMetrics(ctx context.Context, cfg *rest.Config) {
 	}
 }
 
+// addWebhooks will register the admission webhooks for each of the resources with the manager. The webhooks are served
+// on "https://0.0.0.0:webhookPort" once the manager is started.
+func addWebhooks(mgr manager.Manager) error {
+	log.Info("Registering Webhooks.")
+	return (&v1alpha1.ApacheBench{}).SetupWebhookWithManager(mgr)
+}
+
 // serveCRMetrics gets the Operator/CustomResource GVKs and generates metrics based on those types.
 // It serves those metrics on "http://metricsHost:operatorMetricsPort".
 func serveCRMetrics(cfg *rest.Config, operatorNs string) error {
@@ -22,6 +22,13 @@ source ${HACK_DIR}/env.sh
 # Deployment (operator)
 kubectl delete deployment -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}
 
+# Webhook
+kubectl delete validatingwebhookconfiguration ${AB_OPERATOR_NAME}
+kubectl delete service -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}-webhook
+kubectl delete certificate -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}-webhook
+kubectl delete issuer -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}-webhook
+kubectl delete secret -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}-webhook-cert
+
 # Roles/Bindings
 kubectl delete rolebinding -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}
 kubectl delete role -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}
@@ -29,8 +36,9 @@ kubectl delete role -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}
 # ServiceAccount
 kubectl delete sa -n ${AB_OPERATOR_NAMESPACE} ${AB_OPERATOR_NAME}
 
-# CustomResourceDefinition
+# CustomResourceDefinitions
 kubectl delete crd apachebenches.httpd.apache.org
+kubectl delete crd apachebenchsuites.httpd.apache.org
 
 # Scorecard artifacts
 kubectl delete secret -n ${AB_OPERATOR_NAMESPACE} scorecard-kubeconfig
@@ -16,13 +16,302 @@ package v1alpha1
 
 import (
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// ApacheBenchPhasePending indicates that the ApacheBench has been accepted but the benchmark has not started.
+	ApacheBenchPhasePending = "Pending"
+
+	// ApacheBenchPhaseRunning indicates that the benchmark is running.
+	ApacheBenchPhaseRunning = "Running"
+
+	// ApacheBenchPhaseComplete indicates that the benchmark has completed successfully.
+	ApacheBenchPhaseComplete = "Complete"
+
+	// ApacheBenchPhaseFailed indicates that the benchmark has failed.
+	ApacheBenchPhaseFailed = "Failed"
+
+	// ApacheBenchPhaseUnknown indicates that the state of the benchmark could not be obtained.
+	ApacheBenchPhaseUnknown = "Unknown"
+)
+
+// ApacheBenchRunIDAnnotation is the annotation on an ApacheBench that can be changed to start a new run on demand. It
+// is also read from an ApacheBenchSuite, so that changing it runs every benchmark in the suite again.
+const ApacheBenchRunIDAnnotation = "httpd.apache.org/run-id"
+
+const (
+	// ApacheBenchConditionJobCreated indicates whether the Job for the current run has been created.
+	ApacheBenchConditionJobCreated = "JobCreated"
+
+	// ApacheBenchConditionRunning indicates whether the Pods for the current run are running.
+	ApacheBenchConditionRunning = "Running"
+
+	// ApacheBenchConditionSucceeded indicates whether the current run has completed successfully.
+	ApacheBenchConditionSucceeded = "Succeeded"
+
+	// ApacheBenchConditionFailed indicates whether the current run has failed.
+	ApacheBenchConditionFailed = "Failed"
+
+	// ApacheBenchConditionResultsCollected indicates whether the results for the current run have been collected.
+	ApacheBenchConditionResultsCollected = "ResultsCollected"
+
+	// ApacheBenchConditionPassed indicates whether the results for the current run meet the thresholds.
+	ApacheBenchConditionPassed = "Passed"
+
+	// ApacheBenchConditionResultsPushed indicates whether the results for the current run have been pushed to the
+	// Pushgateway.
+	ApacheBenchConditionResultsPushed = "ResultsPushed"
+
+	// ApacheBenchConditionResultsArchived indicates whether the results for the current run have been uploaded to
+	// object storage.
+	ApacheBenchConditionResultsArchived = "ResultsArchived"
+)
+
+const (
+	// ApacheBenchVerdictPassed indicates that the results meet all of the thresholds.
+	ApacheBenchVerdictPassed = "Passed"
+
+	// ApacheBenchVerdictFailed indicates that the results breach at least one threshold, or the run failed.
+	ApacheBenchVerdictFailed = "Failed"
+)
+
+const (
+	// ApacheBenchModeBenchmark runs a single benchmark for each run.
+	ApacheBenchModeBenchmark = "benchmark"
+
+	// ApacheBenchModeCapacitySearch searches for the maximum sustainable concurrency for each run.
+	ApacheBenchModeCapacitySearch = "capacitySearch"
+)
+
+const (
+	// ApacheBenchEndpointModeParallel runs every endpoint at the same time.
+	ApacheBenchEndpointModeParallel = "parallel"
+
+	// ApacheBenchEndpointModeSequential runs each endpoint in turn.
+	ApacheBenchEndpointModeSequential = "sequential"
 )
 
 // NOTE: json tags are required. Any new fields you add must have json tags for the fields to be serialized.
 // Important: Run "operator-sdk generate k8s" to regenerate code after modifying this file
 // Add custom validation using kubebuilder tags: https://book-v1.book.kubebuilder.io/beyond_basics/generating_crd.html
 
+// ApacheBenchBaselineSpec defines the baseline that the results are compared against.
+type ApacheBenchBaselineSpec struct {
+	// MaxRegressionPercent is the percentage by which the throughput may drop, or the latencies may rise, compared with
+	// the baseline before the results are considered a regression. Default is no allowed regression.
+	MaxRegressionPercent float64 `json:"maxRegressionPercent,omitempty"`
+
+	// Name is the name of another ApacheBench in the same namespace to use as the baseline.
+	// When not set, the previous runs of this ApacheBench are used.
+	Name string `json:"name,omitempty"`
+
+	// Run is the number of the run to use as the baseline. Default is the most recently completed run, other than the
+	// current run.
+	Run int32 `json:"run,omitempty"`
+}
+
+// ApacheBenchCapacityIteration defines a single step of the capacity search.
+type ApacheBenchCapacityIteration struct {
+	// Breaches describes each of the limits that were crossed at the concurrency level.
+	Breaches []string `json:"breaches,omitempty"`
+
+	// CompletionTime is the time that the Job for the iteration completed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Concurrency is the number of multiple requests that were performed at a time for the iteration.
+	Concurrency uint32 `json:"concurrency"`
+
+	// Job is the name of the Job for the iteration.
+	Job string `json:"job"`
+
+	// MeanLatency is the mean time per request, in milliseconds, from all of the Job Pods combined.
+	MeanLatency float64 `json:"meanLatency,omitempty"`
+
+	// Number is the sequence number of the iteration, starting at one.
+	Number int32 `json:"number"`
+
+	// RequestsPerSecond is the mean number of requests per second from all of the Job Pods combined.
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty"`
+
+	// Results contains references to the output from each benchmark Job Pod for the iteration.
+	Results []ApacheBenchResultsReference `json:"results,omitempty"`
+
+	// Sustainable is true when the results for the iteration are within all of the limits.
+	Sustainable bool `json:"sustainable"`
+}
+
+// ApacheBenchCapacityResult defines the outcome of a capacity search.
+type ApacheBenchCapacityResult struct {
+	// Concurrency is the maximum sustainable concurrency that was found.
+	// Zero when the limits were crossed at every concurrency level that was tried.
+	Concurrency uint32 `json:"concurrency"`
+
+	// Iterations contains each step of the search, in order.
+	Iterations []ApacheBenchCapacityIteration `json:"iterations,omitempty"`
+
+	// RequestsPerSecond is the mean number of requests per second at the maximum sustainable concurrency.
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+}
+
+// ApacheBenchCapacitySearchSpec defines the options for the capacitySearch mode.
+type ApacheBenchCapacitySearchSpec struct {
+	// Limits defines the latency and error limits that the results must meet for a concurrency level to be
+	// sustainable. The limits are compared with the results from all of the Job Pods combined.
+	Limits *ApacheBenchThresholdsSpec `json:"limits,omitempty"`
+
+	// MaxConcurrency is the highest concurrency level to try. Default is 1000.
+	MaxConcurrency uint32 `json:"maxConcurrency,omitempty"`
+
+	// Resolution is the precision of the binary search, the search stops once the highest sustainable and lowest
+	// unsustainable concurrency levels are no further apart. Default is 1.
+	Resolution uint32 `json:"resolution,omitempty"`
+
+	// StartConcurrency is the first concurrency level to try, which is doubled until a limit is crossed.
+	// Default is 1.
+	StartConcurrency uint32 `json:"startConcurrency,omitempty"`
+}
+
+// ApacheBenchComparison defines the comparison of the current results with a baseline.
+// Each delta is the percentage change from the baseline value.
+type ApacheBenchComparison struct {
+	// Baseline is the name of the ApacheBench that was used as the baseline.
+	Baseline string `json:"baseline"`
+
+	// BaselineRun is the number of the run that was used as the baseline.
+	BaselineRun int32 `json:"baselineRun"`
+
+	// MeanLatencyDelta is the percentage change in the mean time per request.
+	MeanLatencyDelta float64 `json:"meanLatencyDelta"`
+
+	// PercentilesDelta is the percentage change in each of the latency percentiles.
+	// Only reported when both the current and baseline results include the percentiles.
+	PercentilesDelta *ApacheBenchPercentiles `json:"percentilesDelta,omitempty"`
+
+	// Regressed is true when the results have regressed by more than the allowed percentage.
+	Regressed bool `json:"regressed"`
+
+	// Regressions describes each of the values that regressed by more than the allowed percentage.
+	Regressions []string `json:"regressions,omitempty"`
+
+	// RequestsPerSecondDelta is the percentage change in the mean number of requests per second.
+	RequestsPerSecondDelta float64 `json:"requestsPerSecondDelta"`
+}
+
+// ApacheBenchCondition defines an observation of the state of an ApacheBench.
+type ApacheBenchCondition struct {
+	// LastTransitionTime is the last time that the condition changed from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// Message is a human readable message with details about the transition.
+	Message string `json:"message,omitempty"`
+
+	// ObservedGeneration is the generation of the ApacheBench that the condition was set for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Reason is a brief, CamelCase reason for the last transition of the condition.
+	Reason string `json:"reason"`
+
+	// Status is the status of the condition (True, False or Unknown).
+	// +kubebuilder:validation:Enum=True;False;Unknown
+	Status corev1.ConditionStatus `json:"status"`
+
+	// Type is the type of the condition (JobCreated, Running, Succeeded, Failed, ResultsCollected, Passed,
+	// ResultsPushed or ResultsArchived).
+	Type string `json:"type"`
+}
+
+// ApacheBenchConnectionTimes defines the connection times, in milliseconds, reported for a single part of a request.
+type ApacheBenchConnectionTimes struct {
+	// Max is the maximum time.
+	Max float64 `json:"max"`
+
+	// Mean is the mean time.
+	Mean float64 `json:"mean"`
+
+	// Median is the median time. Not reported when the median is disabled.
+	Median float64 `json:"median"`
+
+	// Min is the minimum time.
+	Min float64 `json:"min"`
+
+	// StdDev is the standard deviation of the time. Not reported when the median is disabled.
+	StdDev float64 `json:"stdDev"`
+}
+
+// ApacheBenchConnectionTimesSummary defines the "Connection Times (ms)" table reported by ab.
+type ApacheBenchConnectionTimesSummary struct {
+	// Connect is the time spent establishing the connection.
+	Connect ApacheBenchConnectionTimes `json:"connect"`
+
+	// Processing is the time spent processing the request after the connection was established.
+	Processing ApacheBenchConnectionTimes `json:"processing"`
+
+	// Total is the total time spent for the request.
+	Total ApacheBenchConnectionTimes `json:"total"`
+
+	// Waiting is the time spent waiting for the first byte of the response.
+	Waiting ApacheBenchConnectionTimes `json:"waiting"`
+}
+
+// ApacheBenchEndpointResult defines the combined results for a single endpoint of the benchmark.
+type ApacheBenchEndpointResult struct {
+	// Aggregate contains the results for the endpoint from all of the Job Pods combined.
+	Aggregate *ApacheBenchSummary `json:"aggregate,omitempty"`
+
+	// Concurrency is the number of multiple requests that were performed at a time for the endpoint.
+	Concurrency uint32 `json:"concurrency"`
+
+	// Name is the name of the endpoint.
+	Name string `json:"name"`
+
+	// Requests is the number of requests that were performed for the endpoint.
+	Requests uint32 `json:"requests,omitempty"`
+
+	// TimeLimit is the maximum number of seconds that were spent on the endpoint.
+	TimeLimit uint32 `json:"timeLimit,omitempty"`
+
+	// URL is the HTTP endpoint that was benchmarked.
+	URL string `json:"url"`
+}
+
+// ApacheBenchEndpointSpec defines a single HTTP endpoint to benchmark along with the others.
+type ApacheBenchEndpointSpec struct {
+	// ContentType is the Content-type header to use for POST/PUT data for the endpoint.
+	// Default is the ContentType property of the benchmark.
+	ContentType string `json:"contentType,omitempty"`
+
+	// Headers is a map of key-value pairs to add as headers to the requests for the endpoint, along with the Headers
+	// property of the benchmark. A header with the same name replaces the header from the benchmark.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// HTTPMethod is a custom HTTP method for the requests to the endpoint.
+	// Default is the HTTPMethod property of the benchmark.
+	HTTPMethod string `json:"httpMethod,omitempty"`
+
+	// Name is the name of the endpoint, which identifies the results for the endpoint.
+	Name string `json:"name"`
+
+	// POSTDataKey is the name of the key in the ConfigMap specified in the ConfigMapName property that contains data
+	// to POST with each request to the endpoint.
+	POSTDataKey string `json:"postDataKey,omitempty"`
+
+	// PUTDataKey is the name of the key in the ConfigMap specified in the ConfigMapName property that contains data
+	// to PUT with each request to the endpoint.
+	PUTDataKey string `json:"putDataKey,omitempty"`
+
+	// URL is the HTTP endpoint to benchmark. A path that starts with a slash, eg. /api/v1/items, is appended to the
+	// URL or Target of the benchmark.
+	URL string `json:"url"`
+
+	// Weight is the share of the requests for the endpoint relative to the other endpoints. When the endpoints are
+	// run in parallel the concurrency is shared by weight, otherwise the time limit is shared by weight. Default is 1.
+	// +kubebuilder:validation:Minimum=1
+	Weight uint32 `json:"weight,omitempty"`
+}
+
 // ApacheBenchHTMLSpec defines the options for HTML output.
 type ApacheBenchHTMLSpec struct {
 	// Enabled toggles the printing of results in HTML tables.
@@ -39,6 +328,187 @@ type ApacheBenchHTMLSpec struct {
 	TR string `json:"tr,omitempty"`
 }
 
+// ApacheBenchHTTPProbeSpec defines an HTTP probe that must succeed before the benchmark starts. The probe is sent by an
+// init container in the benchmark Pod, so that it reaches the URL in the same way as the benchmark.
+type ApacheBenchHTTPProbeSpec struct {
+	// ExpectedStatus is the HTTP status code that indicates success.
+	// Defaults to any status from 200 to 399, as for a Kubernetes HTTP probe.
+	ExpectedStatus int32 `json:"expectedStatus,omitempty"`
+
+	// Interval is the time between attempts, eg. 10s, rounded up to whole seconds. Defaults to 5s.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// Timeout is the time to wait for a response to each attempt, eg. 2s, rounded up to whole seconds. Defaults to 5s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// URL is the URL to probe. Defaults to the URL to benchmark, or the URL of each endpoint.
+	URL string `json:"url,omitempty"`
+}
+
+// ApacheBenchOutputSpec defines the destinations that the results are sent to once a run completes.
+type ApacheBenchOutputSpec struct {
+	// Pushgateway defines a Prometheus Pushgateway to push the results to.
+	Pushgateway *ApacheBenchPushgatewaySpec `json:"pushgateway,omitempty"`
+
+	// S3 defines S3-compatible object storage to archive the results to.
+	S3 *ApacheBenchS3Spec `json:"s3,omitempty"`
+
+	// VolumeClaim defines a PersistentVolumeClaim that the benchmark Pods write the results to, including the gnuplot
+	// and CSV files.
+	VolumeClaim *ApacheBenchVolumeClaimSpec `json:"volumeClaim,omitempty"`
+}
+
+// ApacheBenchPercentiles defines the "Percentage of the requests served within a certain time (ms)" table reported
+// by ab. Each value is the time, in milliseconds, within which the given percentage of requests were served.
+type ApacheBenchPercentiles struct {
+	P50  float64 `json:"p50"`
+	P66  float64 `json:"p66"`
+	P75  float64 `json:"p75"`
+	P80  float64 `json:"p80"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P98  float64 `json:"p98"`
+	P99  float64 `json:"p99"`
+	P100 float64 `json:"p100"`
+}
+
+// ReservedPushgatewayLabels are the names of the labels that are set by the operator on the metrics pushed to a
+// Pushgateway, or on their grouping, and so cannot be used as grouping labels.
+var ReservedPushgatewayLabels = []string{"instance", "job", "name", "namespace", "quantile", "run"}
+
+// ApacheBenchPushgatewaySpec defines a Prometheus Pushgateway to push the results to.
+type ApacheBenchPushgatewaySpec struct {
+	// GroupingLabels are additional labels used to group the pushed metrics. By default the metrics are grouped by an
+	// "instance" label set to the namespace and name of the ApacheBench. The labels set by the operator, listed in
+	// ReservedPushgatewayLabels, cannot be used.
+	GroupingLabels map[string]string `json:"groupingLabels,omitempty"`
+
+	// Job is the job name used to group the pushed metrics. Default is "apachebench".
+	Job string `json:"job,omitempty"`
+
+	// URL is the base URL of the Pushgateway, eg. http://pushgateway.monitoring.svc:9091.
+	URL string `json:"url"`
+}
+
+// ApacheBenchReadinessSpec defines the checks that must pass before the Job for each run is created.
+type ApacheBenchReadinessSpec struct {
+	// Deployment is the name of a Deployment in the namespace of the ApacheBench that must complete its rollout.
+	Deployment string `json:"deployment,omitempty"`
+
+	// HTTPGet defines an HTTP probe that must succeed.
+	HTTPGet *ApacheBenchHTTPProbeSpec `json:"httpGet,omitempty"`
+
+	// Timeout is the maximum time to wait for the checks to pass once the run has started, eg. 10m. This includes the
+	// time to wait for the Target to become ready. The run fails if the checks have not passed in time.
+	// Defaults to 5m.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+// ApacheBenchResultsReference defines where the output from a benchmark Job Pod is stored.
+type ApacheBenchResultsReference struct {
+	// ConfigMaps are the names of the ConfigMaps that contain the output, in order. Large output is split across
+	// multiple ConfigMaps, and the complete output is the concatenation of the "output" key from each ConfigMap.
+	// The output is stored as binary data when it is not valid UTF-8.
+	ConfigMaps []string `json:"configMaps"`
+
+	// CSVConfigMaps are the names of the ConfigMaps that contain the CSV file, stored in the same way as the output.
+	CSVConfigMaps []string `json:"csvConfigMaps,omitempty"`
+
+	// GnuplotConfigMaps are the names of the ConfigMaps that contain the gnuplot file, stored in the same way as the
+	// output.
+	GnuplotConfigMaps []string `json:"gnuplotConfigMaps,omitempty"`
+
+	// Pod is the name of the benchmark Job Pod that produced the output.
+	Pod string `json:"pod"`
+
+	// Size is the size of the complete output in bytes.
+	Size int64 `json:"size"`
+}
+
+// ApacheBenchRun defines a single run of the benchmark.
+type ApacheBenchRun struct {
+	// Aggregate contains the results from all of the Job Pods for the run combined.
+	Aggregate *ApacheBenchSummary `json:"aggregate,omitempty"`
+
+	// ArchivedObjects contains the keys of the objects that the results for the run were uploaded to.
+	ArchivedObjects []string `json:"archivedObjects,omitempty"`
+
+	// ArchiveTime is the time that the results for the run were uploaded to object storage.
+	ArchiveTime *metav1.Time `json:"archiveTime,omitempty"`
+
+	// Capacity contains the outcome of the capacity search for the run.
+	Capacity *ApacheBenchCapacityResult `json:"capacity,omitempty"`
+
+	// CompletionTime is the time that the run completed.
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Endpoints contains the combined results for each endpoint for the run, in order.
+	Endpoints []ApacheBenchEndpointResult `json:"endpoints,omitempty"`
+
+	// Job is the name of the Job for the run. For a capacity search, this is the Job for the current iteration.
+	Job string `json:"job"`
+
+	// Number is the sequence number of the run, starting at one.
+	Number int32 `json:"number"`
+
+	// Phase is a simple, high-level summary of where the run is in its lifecycle.
+	// See the Phase property on the ApacheBenchStatus for the possible values.
+	Phase string `json:"phase"`
+
+	// PushTime is the time that the results for the run were pushed to the Pushgateway.
+	PushTime *metav1.Time `json:"pushTime,omitempty"`
+
+	// Results contains references to the output from each benchmark Job Pod for the run.
+	Results []ApacheBenchResultsReference `json:"results,omitempty"`
+
+	// RunID is the value of the "httpd.apache.org/run-id" annotation on the ApacheBench when the run was started.
+	RunID string `json:"runID,omitempty"`
+
+	// ScheduledTime is the time that the run was scheduled for, when started by the Schedule.
+	ScheduledTime *metav1.Time `json:"scheduledTime,omitempty"`
+
+	// SpecHash is the hash of the ApacheBench spec that was used for the run.
+	SpecHash string `json:"specHash,omitempty"`
+
+	// Stages contains the combined results for each stage of the load profile for the run, in order.
+	Stages []ApacheBenchStageResult `json:"stages,omitempty"`
+
+	// StartTime is the time that the run was started.
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Trigger is the reason that the run was started (Initial, Schedule, SpecChange or Manual).
+	Trigger string `json:"trigger,omitempty"`
+
+	// URL is the HTTP endpoint that was benchmarked for the run, as resolved from the Target when set.
+	URL string `json:"url,omitempty"`
+
+	// Verdict is the result of comparing the run against the thresholds (Passed or Failed).
+	Verdict string `json:"verdict,omitempty"`
+
+	// VolumePath is the directory within the PersistentVolumeClaim that the results for the run are written to.
+	VolumePath string `json:"volumePath,omitempty"`
+}
+
+// ApacheBenchS3Spec defines S3-compatible object storage to archive the results to.
+type ApacheBenchS3Spec struct {
+	// Bucket is the name of the bucket to upload the results to.
+	Bucket string `json:"bucket"`
+
+	// CredentialsSecret is the name of a Secret that contains the "accessKeyID" and "secretAccessKey" properties used
+	// to sign requests. The optional "sessionToken" property is used for temporary credentials.
+	CredentialsSecret string `json:"credentialsSecret"`
+
+	// Endpoint is the URL of the S3-compatible service, eg. https://s3.us-east-1.amazonaws.com.
+	// Requests use path-style addressing, so that the bucket name is not required to resolve as a host name.
+	Endpoint string `json:"endpoint"`
+
+	// Prefix is prepended to the key of each uploaded object.
+	Prefix string `json:"prefix,omitempty"`
+
+	// Region is the region used to sign requests. Default is "us-east-1".
+	Region string `json:"region,omitempty"`
+}
+
 // ApacheBenchSpec defines the desired state of ApacheBench
 type ApacheBenchSpec struct {
 	// Authenticate enables authentication for requests.
@@ -51,9 +521,20 @@ type ApacheBenchSpec struct {
 	// the SecretName property.
 	AuthenticateProxy bool `json:"authenticateProxy,omitempty"`
 
+	// Baseline defines the baseline that the results are compared against once a run completes.
+	// Changes to the baseline are applied to the current results and do not start a new run.
+	Baseline *ApacheBenchBaselineSpec `json:"baseline,omitempty"`
+
+	// CapacitySearch defines the options for the capacitySearch mode.
+	CapacitySearch *ApacheBenchCapacitySearchSpec `json:"capacitySearch,omitempty"`
+
 	// Cookies is a map of key-value pairs to add as Cookie: lines to the request.
 	Cookies map[string]string `json:"cookies,omitempty"`
 
+	// CookiesFrom is a list of cookies to add to the request, with values from a Secret or ConfigMap key.
+	// The values are resolved when the benchmark Pod starts and do not appear in the Job spec.
+	CookiesFrom []ApacheBenchValueFromSource `json:"cookiesFrom,omitempty"`
+
 	// Concurrency is the number of multiple requests to perform at a time. Default is one request at a time.
 	Concurrency uint32 `json:"concurrency,omitempty"`
 
@@ -64,6 +545,10 @@ type ApacheBenchSpec struct {
 	// Default is text/plain.
 	ContentType string `json:"contentType,omitempty"`
 
+	// CSV enables the CSV file that contains the time within which each percentage of the requests, from 0% to 100%,
+	// was served. The file is collected from each benchmark Job Pod and stored with the results.
+	CSV bool `json:"csv,omitempty"`
+
 	// OmitLengthErrors disables errors if the length of the responses is not constant. This can be useful for dynamic pages.
 	DisableLengthErrors bool `json:"disableLengthErrors,omitempty"`
 
@@ -84,9 +569,31 @@ type ApacheBenchSpec struct {
 	// EnableHEADRequests enables HEAD requests instead of GET.
 	EnableHEADRequests bool `json:"enableHEADRequests,omitempty"`
 
+	// EndpointMode is how the endpoints are run by each benchmark Pod. There are two possible values:
+	// sequential: Each endpoint is run in turn. This is the default.
+	// parallel: Every endpoint is run at the same time.
+	// +kubebuilder:validation:Enum=parallel;sequential
+	EndpointMode string `json:"endpointMode,omitempty"`
+
+	// Endpoints is a list of HTTP endpoints to benchmark, each with its own URL, method, headers, data and weight.
+	// The Requests, Concurrency and TimeLimit properties are shared between the endpoints by weight, and the results
+	// are reported for each endpoint as well as in total. The URL and Target properties are optional when there are
+	// endpoints.
+	Endpoints []ApacheBenchEndpointSpec `json:"endpoints,omitempty"`
+
+	// Gnuplot enables the gnuplot (TSV) file that contains the timings for every request. The file is collected from
+	// each benchmark Job Pod and stored with the results, and is used to calculate exact percentiles when the results
+	// from multiple Pods are combined.
+	Gnuplot bool `json:"gnuplot,omitempty"`
+
 	// Headers is a map of key-value pairs to add as headers to the request.
 	Headers map[string]string `json:"headers,omitempty"`
 
+	// HeadersFrom is a list of headers to add to the request, with values from a Secret or ConfigMap key, eg. an
+	// Authorization header with a bearer token. The values are resolved when the benchmark Pod starts and do not
+	// appear in the Job spec.
+	HeadersFrom []ApacheBenchValueFromSource `json:"headersFrom,omitempty"`
+
 	// HTML defines the HTML output options.
 	HTML ApacheBenchHTMLSpec `json:"html,omitempty"`
 
@@ -102,6 +609,17 @@ type ApacheBenchSpec struct {
 	// KeepAlive enables the HTTP KeepAlive feature, i.e., perform multiple requests within one HTTP session.
 	KeepAlive bool `json:"keepAlive,omitempty"`
 
+	// Mode is the mode of operation for each run. There are two possible values:
+	// benchmark: A single benchmark is run with the given options. This is the default.
+	// capacitySearch: The benchmark is run repeatedly at increasing concurrency, doubling and then using a binary
+	// search, to find the maximum concurrency that is sustainable within the limits set in the CapacitySearch property.
+	// +kubebuilder:validation:Enum=benchmark;capacitySearch
+	Mode string `json:"mode,omitempty"`
+
+	// Output defines the destinations that the results are sent to once a run completes.
+	// Changes to the output do not start a new run.
+	Output *ApacheBenchOutputSpec `json:"output,omitempty"`
+
 	// POSTDataKey is the name of the key in the ConfigMap specified in the ConfigMapName property that contains data
 	// to POST with each request.
 	POSTDataKey string `json:"postDataKey,omitempty"`
@@ -113,13 +631,44 @@ type ApacheBenchSpec struct {
 	// to PUT with each request.
 	PUTDataKey string `json:"putDataKey,omitempty"`
 
+	// Readiness defines the checks that must pass before the benchmark starts, eg. to wait for a new deployment of the
+	// application. The run remains Pending until the checks pass, and fails if they do not pass before the timeout.
+	Readiness *ApacheBenchReadinessSpec `json:"readiness,omitempty"`
+
 	// Requests is the number of requests to perform for the benchmarking session.
 	// The default is to just perform a single request which usually leads to non-representative benchmarking results.
 	Requests uint32 `json:"requests,omitempty"`
 
+	// RerunOnReferenceChange enables a new run when the value of a Secret or ConfigMap key that is referenced by the
+	// HeadersFrom or CookiesFrom properties changes, eg. when a token is rotated.
+	RerunOnReferenceChange bool `json:"rerunOnReferenceChange,omitempty"`
+
+	// RunHistoryLimit is the number of runs to keep in the status, along with their Jobs. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	RunHistoryLimit *int32 `json:"runHistoryLimit,omitempty"`
+
+	// Schedule is a cron expression, eg. "0 2 * * *", to run the benchmark on a recurring basis.
+	// The standard five fields (minute, hour, day of month, month and day of week) are supported, as well as the
+	// @yearly, @monthly, @weekly, @daily and @hourly descriptors. When set, the benchmark is not run until the first
+	// scheduled time. A scheduled run is skipped while a previous run is still in progress.
+	Schedule string `json:"schedule,omitempty"`
+
 	// SecretName is the name of the Secret containing authentication credentials and/or the client certificate.
 	SecretName string `json:"secretName,omitempty"`
 
+	// Stages defines a load profile of steps that are run in sequence, eg. with increasing concurrency, in place of the
+	// Concurrency, Requests and TimeLimit properties. The results are recorded for each stage.
+	Stages []ApacheBenchStageSpec `json:"stages,omitempty"`
+
+	// Target defines a Service, Ingress or Route to benchmark instead of the URL property.
+	// The target is resolved to a URL when the Job for each run is created, once the backing Service has ready
+	// endpoints.
+	Target *ApacheBenchTargetSpec `json:"target,omitempty"`
+
+	// Thresholds defines the limits that the results must meet for the benchmark to pass.
+	// Changes to the thresholds are applied to the current results and do not start a new run.
+	Thresholds *ApacheBenchThresholdsSpec `json:"thresholds,omitempty"`
+
 	// TimeLimit is the maximum number of seconds to spend for benchmarking.
 	// This implies a 50000 value for Requests. Use this to benchmark the server within a fixed total amount of time.
 	// Per default there is no timelimit.
@@ -131,8 +680,9 @@ type ApacheBenchSpec struct {
 	// TLS defines the options for TLS connections.
 	TLS ApacheBenchTLSSpec `json:"tls,omitempty"`
 
-	// URL is the HTTP endpoint to benchmark.
-	URL string `json:"url"`
+	// URL is the HTTP endpoint to benchmark, including a path, eg. http://example.com/. Either the URL or Target
+	// property must be set, unless there are endpoints.
+	URL string `json:"url,omitempty"`
 
 	// Verbosity is the verbosity level.
 	// 4 and above prints information on headers.
@@ -140,15 +690,73 @@ type ApacheBenchSpec struct {
 	// 2 and above prints warnings and info.
 	Verbosity uint32 `json:"verbosity,omitempty"`
 
+	// Warmup defines a preliminary run of ab against the same URL before the benchmark, eg. to warm up caches or a JIT
+	// compiler. The results of the warm-up are discarded.
+	Warmup *ApacheBenchWarmupSpec `json:"warmup,omitempty"`
+
 	// WindowSize is the size of TCP send/receive buffer, in bytes.
 	WindowSize uint32 `json:"windowSize,omitempty"`
 }
 
+// ApacheBenchStageResult defines the combined results for a single stage of the load profile.
+type ApacheBenchStageResult struct {
+	// Aggregate contains the results for the stage from all of the Job Pods combined.
+	Aggregate *ApacheBenchSummary `json:"aggregate,omitempty"`
+
+	// Concurrency is the number of multiple requests that were performed at a time for the stage.
+	Concurrency uint32 `json:"concurrency"`
+
+	// Number is the sequence number of the stage, starting at one.
+	Number int32 `json:"number"`
+
+	// Requests is the number of requests that were performed for the stage.
+	Requests uint32 `json:"requests,omitempty"`
+
+	// TimeLimit is the maximum number of seconds that were spent on the stage.
+	TimeLimit uint32 `json:"timeLimit,omitempty"`
+}
+
+// ApacheBenchStageSpec defines a single step of the load profile.
+type ApacheBenchStageSpec struct {
+	// Concurrency is the number of multiple requests to perform at a time for the stage.
+	// +kubebuilder:validation:Minimum=1
+	Concurrency uint32 `json:"concurrency"`
+
+	// Requests is the number of requests to perform for the stage.
+	Requests uint32 `json:"requests,omitempty"`
+
+	// TimeLimit is the maximum number of seconds to spend on the stage. When Requests is also set, the stage ends once
+	// either is reached.
+	TimeLimit uint32 `json:"timeLimit,omitempty"`
+}
+
 // ApacheBenchStatus defines the observed state of ApacheBench
 type ApacheBenchStatus struct {
-	// Errors contains any errors that prevented the completion of the benchmark Job(s).
+	// Aggregate contains the results from all benchmark Job Pods combined.
+	// Counts and throughput are summed, while latencies are weighted by the number of completed requests in each Pod.
+	Aggregate *ApacheBenchSummary `json:"aggregate,omitempty"`
+
+	// Capacity contains the outcome of the capacity search for the most recently completed run.
+	// The ResultsRefs, Summary and Aggregate properties contain the results at the maximum sustainable concurrency.
+	Capacity *ApacheBenchCapacityResult `json:"capacity,omitempty"`
+
+	// Comparison contains the comparison of the results for the current run with the baseline.
+	Comparison *ApacheBenchComparison `json:"comparison,omitempty"`
+
+	// Conditions contains the latest observations of the state of the current run.
+	Conditions []ApacheBenchCondition `json:"conditions,omitempty"`
+
+	// Endpoints contains the combined results for each endpoint, in order.
+	// When there are endpoints, the Summary and Aggregate properties contain the results for every endpoint combined.
+	Endpoints []ApacheBenchEndpointResult `json:"endpoints,omitempty"`
+
+	// Errors contains any errors that prevented the completion of the benchmark Job(s) for the current run. The errors
+	// are cleared when a new run starts.
 	Errors []string `json:"errors,omitempty"`
 
+	// LastScheduleTime is the last time that a run was scheduled by the Schedule.
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
 	// Phase is a simple, high-level summary of where the ApacheBench is in its lifecycle.
 	// There are five possible phase values:
 	// Pending: The ApacheBench has been accepted by the Kubernetes system.
@@ -159,7 +767,139 @@ type ApacheBenchStatus struct {
 	Phase string `json:"phase"`
 
 	// Results contains the result output from each benchmark Job.
+	// Deprecated: The output is no longer stored in the status, see ResultsRefs.
 	Results []string `json:"results,omitempty"`
+
+	// ResultsRefs contains references to the ConfigMaps that store the output from each benchmark Job Pod.
+	ResultsRefs []ApacheBenchResultsReference `json:"resultsRefs,omitempty"`
+
+	// Runs contains the history of benchmark runs, with the most recent run last.
+	// The ResultsRefs, Stages, Summary and Aggregate properties refer to the most recently completed run.
+	Runs []ApacheBenchRun `json:"runs,omitempty"`
+
+	// Stages contains the combined results for each stage of the load profile, in order.
+	// When there are stages, the Summary and Aggregate properties contain the results for the final stage.
+	Stages []ApacheBenchStageResult `json:"stages,omitempty"`
+
+	// Summary contains the parsed results from each benchmark Job Pod.
+	Summary []ApacheBenchSummary `json:"summary,omitempty"`
+
+	// ThresholdBreaches describes each of the thresholds that were breached by the current run.
+	ThresholdBreaches []string `json:"thresholdBreaches,omitempty"`
+
+	// Verdict is the result of comparing the current run against the thresholds (Passed or Failed).
+	// Not set when there are no thresholds, or while the run is in progress.
+	Verdict string `json:"verdict,omitempty"`
+}
+
+// ApacheBenchSummary defines the parsed report from a single run of ab.
+type ApacheBenchSummary struct {
+	// CompleteRequests is the number of requests that completed.
+	CompleteRequests int64 `json:"completeRequests"`
+
+	// ConcurrencyLevel is the number of requests that were performed at a time.
+	ConcurrencyLevel int32 `json:"concurrencyLevel"`
+
+	// ConnectionTimes is the breakdown of the connection times for the requests.
+	ConnectionTimes *ApacheBenchConnectionTimesSummary `json:"connectionTimes,omitempty"`
+
+	// DocumentLength is the length of the first successful response, in bytes.
+	DocumentLength int64 `json:"documentLength"`
+
+	// DocumentPath is the path of the benchmarked URL.
+	DocumentPath string `json:"documentPath,omitempty"`
+
+	// FailedRequests is the number of requests that were considered a failure.
+	FailedRequests int64 `json:"failedRequests"`
+
+	// HTMLTransferred is the total number of document body bytes received from the server.
+	HTMLTransferred int64 `json:"htmlTransferred"`
+
+	// KeepAliveRequests is the number of requests that resulted in a KeepAlive connection.
+	KeepAliveRequests int64 `json:"keepAliveRequests"`
+
+	// Non2xxResponses is the number of responses with a status code outside of the 200 series.
+	Non2xxResponses int64 `json:"non2xxResponses"`
+
+	// Percentiles is the distribution of the request times. Not reported when the percentage served table is disabled.
+	Percentiles *ApacheBenchPercentiles `json:"percentiles,omitempty"`
+
+	// Pod is the name of the Pod that produced the report.
+	Pod string `json:"pod,omitempty"`
+
+	// RequestsPerSecond is the mean number of requests per second.
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+
+	// ServerHostname is the hostname of the benchmarked server.
+	ServerHostname string `json:"serverHostname,omitempty"`
+
+	// ServerPort is the port of the benchmarked server.
+	ServerPort int32 `json:"serverPort,omitempty"`
+
+	// ServerSoftware is the value of the Server header in the first successful response.
+	ServerSoftware string `json:"serverSoftware,omitempty"`
+
+	// TimePerRequest is the mean time per request, in milliseconds.
+	TimePerRequest float64 `json:"timePerRequest"`
+
+	// TimePerRequestAcrossConcurrency is the mean time per request across all concurrent requests, in milliseconds.
+	TimePerRequestAcrossConcurrency float64 `json:"timePerRequestAcrossConcurrency"`
+
+	// TimeTaken is the time taken for the benchmark, in seconds.
+	TimeTaken float64 `json:"timeTaken"`
+
+	// TotalTransferred is the total number of bytes received from the server, including headers.
+	TotalTransferred int64 `json:"totalTransferred"`
+
+	// TransferRate is the rate of data received from the server, in kilobytes per second.
+	TransferRate float64 `json:"transferRate"`
+
+	// WriteErrors is the number of requests that failed while sending the request.
+	WriteErrors int64 `json:"writeErrors"`
+}
+
+// ApacheBenchTargetSpec defines a Service, Ingress or Route in the namespace of the ApacheBench to benchmark.
+type ApacheBenchTargetSpec struct {
+	// Kind is the kind of the target (Service, Ingress or Route).
+	// +kubebuilder:validation:Enum=Service;Ingress;Route
+	Kind string `json:"kind"`
+
+	// Name is the name of the target.
+	Name string `json:"name"`
+
+	// Path is the path to request, eg. /healthz. Defaults to /.
+	Path string `json:"path,omitempty"`
+
+	// Port is the name or number of the Service port to benchmark. Defaults to the first port of the Service.
+	// Only used when the Kind is Service.
+	Port *intstr.IntOrString `json:"port,omitempty"`
+
+	// Scheme is the scheme for the URL (http or https). For a Service, the default is https when the port is 443 or
+	// named "https". For an Ingress or Route, the default is https when TLS is configured for the host.
+	// +kubebuilder:validation:Enum=http;https
+	Scheme string `json:"scheme,omitempty"`
+}
+
+// ApacheBenchThresholdsSpec defines the limits that the results must meet for the benchmark to pass.
+// The limits are compared with the results from all of the Job Pods combined.
+type ApacheBenchThresholdsSpec struct {
+	// MaxFailedRequestRatio is the maximum ratio of failed requests to completed requests, eg. 0.01 for 1%.
+	MaxFailedRequestRatio *float64 `json:"maxFailedRequestRatio,omitempty"`
+
+	// MaxMeanLatency is the maximum mean time per request, in milliseconds.
+	MaxMeanLatency *float64 `json:"maxMeanLatency,omitempty"`
+
+	// MaxNon2xxResponses is the maximum number of responses with a status code outside of the 200 series.
+	MaxNon2xxResponses *int64 `json:"maxNon2xxResponses,omitempty"`
+
+	// MaxP95Latency is the maximum time, in milliseconds, within which 95% of requests must be served.
+	MaxP95Latency *float64 `json:"maxP95Latency,omitempty"`
+
+	// MaxP99Latency is the maximum time, in milliseconds, within which 99% of requests must be served.
+	MaxP99Latency *float64 `json:"maxP99Latency,omitempty"`
+
+	// MinRequestsPerSecond is the minimum mean number of requests per second.
+	MinRequestsPerSecond *float64 `json:"minRequestsPerSecond,omitempty"`
 }
 
 // ApacheBenchTLSSpec defines the options for TLS connections.
@@ -167,11 +907,53 @@ type ApacheBenchTLSSpec struct {
 	// CipherSuite is the SSL/TLS cipher suite (See openssl ciphers).
 	CipherSuite string `json:"cipherSuite,omitempty"`
 
+	// ClientCertificateKey is the property in the Secret that is referenced by the SecretName property that contains
+	// the client certificate to use for mutual TLS. The certificate and private key must both be present in the
+	// property, in PEM format.
+	ClientCertificateKey string `json:"clientCertificateKey,omitempty"`
+
 	// Protocol is the SSL/TLS protocol.
-	// (SSL2, SSL3, TLS1, TLS1.1, TLS1.2, or ALL). TLS1.1 and TLS1.2
+	// (SSL2, SSL3, TLS1, TLS1.1, TLS1.2, TLS1.3 or ALL). TLS1.3 requires a build of ab that supports it.
 	Protocol string `json:"protocol,omitempty"`
 }
 
+// ApacheBenchValueFromSource defines a named value, such as a header or cookie, that is read from a Secret or
+// ConfigMap key. Exactly one of SecretKeyRef or ConfigMapKeyRef must be set.
+type ApacheBenchValueFromSource struct {
+	// ConfigMapKeyRef selects a key of a ConfigMap in the namespace of the ApacheBench.
+	ConfigMapKeyRef *corev1.ConfigMapKeySelector `json:"configMapKeyRef,omitempty"`
+
+	// Name is the name of the header or cookie.
+	Name string `json:"name"`
+
+	// SecretKeyRef selects a key of a Secret in the namespace of the ApacheBench.
+	SecretKeyRef *corev1.SecretKeySelector `json:"secretKeyRef,omitempty"`
+}
+
+// ApacheBenchVolumeClaimSpec defines a PersistentVolumeClaim that the results are written to.
+type ApacheBenchVolumeClaimSpec struct {
+	// ClaimName is the name of a PersistentVolumeClaim in the same namespace as the ApacheBench.
+	ClaimName string `json:"claimName"`
+
+	// SubPath is the path within the volume to write the results under. Default is the root of the volume.
+	// The results for each run are written to the "<name>/<run>" directory under this path.
+	SubPath string `json:"subPath,omitempty"`
+}
+
+// ApacheBenchWarmupSpec defines a preliminary run of ab, using the same options as the benchmark except for those
+// below, that is excluded from the results.
+type ApacheBenchWarmupSpec struct {
+	// Concurrency is the number of multiple requests to perform at a time during the warm-up. It cannot be greater
+	// than Requests unless TimeLimit is set. Defaults to the Concurrency of the benchmark, limited to Requests.
+	Concurrency uint32 `json:"concurrency,omitempty"`
+
+	// Requests is the number of requests to perform during the warm-up.
+	Requests uint32 `json:"requests,omitempty"`
+
+	// TimeLimit is the maximum number of seconds to spend warming up.
+	TimeLimit uint32 `json:"timeLimit,omitempty"`
+}
+
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 // ApacheBench is the Schema for the apachebenches API
@@ -197,3 +979,24 @@ type ApacheBenchList struct {
 func init() {
 	SchemeBuilder.Register(&ApacheBench{}, &ApacheBenchList{})
 }
+
+// GetCurrentRun will return the most recent run for the ApacheBench, or nil if there are no runs.
+func (r *ApacheBench) GetCurrentRun() *ApacheBenchRun {
+	if len(r.Status.Runs) <= 0 {
+		return nil
+	}
+	return &r.Status.Runs[len(r.Status.Runs)-1]
+}
+
+// AddError will add the given error message to the status, if not already present.
+func (s *ApacheBenchStatus) AddError(msg string) {
+	s.Errors = appendError(s.Errors, msg)
+}
+
+// appendError will return the given error messages with the given message added, if not already present.
+func appendError(errs []string, msg string) []string {
+	if containsString(errs, msg) {
+		return errs
+	}
+	return append(errs, msg)
+}
@@ -0,0 +1,220 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"net/url"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// tlsProtocols are the SSL/TLS protocols that are supported by ab.
+var tlsProtocols = []string{"ALL", "SSL2", "SSL3", "TLS1", "TLS1.1", "TLS1.2", "TLS1.3"}
+
+// blank assignment to verify that ApacheBench implements webhook.Validator
+var _ webhook.Validator = &ApacheBench{}
+
+// containsString will return true if the given list contains the given value.
+func containsString(list []string, value string) bool {
+	for _, s := range list {
+		if s == value {
+			return true
+		}
+	}
+	return false
+}
+
+// +kubebuilder:webhook:path=/validate-httpd-apache-org-v1alpha1-apachebench,mutating=false,failurePolicy=fail,groups=httpd.apache.org,resources=apachebenches,verbs=create;update,versions=v1alpha1,name=vapachebench.httpd.apache.org
+
+// SetupWebhookWithManager will register the validating webhook for ApacheBench with the given Manager.
+func (r *ApacheBench) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).For(r).Complete()
+}
+
+// ValidateCreate will check the spec of a new ApacheBench and return an error describing each invalid field.
+func (r *ApacheBench) ValidateCreate() error {
+	return r.validate()
+}
+
+// ValidateDelete will allow any ApacheBench to be deleted.
+func (r *ApacheBench) ValidateDelete() error {
+	return nil
+}
+
+// ValidateUpdate will check the spec of an updated ApacheBench and return an error describing each invalid field.
+func (r *ApacheBench) ValidateUpdate(old runtime.Object) error {
+	return r.validate()
+}
+
+// validate will return an error describing each invalid field in the spec of the ApacheBench, or nil if the spec is
+// valid. Only the problems that can be found without reading other resources are checked here, the rest are reported
+// in the status by the controller.
+func (r *ApacheBench) validate() error {
+	errs := validateApacheBenchSpec(&r.Spec, field.NewPath("spec"))
+	if len(errs) <= 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(SchemeGroupVersion.WithKind("ApacheBench").GroupKind(), r.Name, errs)
+}
+
+// validateApacheBenchSpec will return the errors for the given spec at the given path.
+func validateApacheBenchSpec(spec *ApacheBenchSpec, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	switch {
+	case len(spec.URL) > 0 && spec.Target != nil:
+		errs = append(errs, field.Invalid(path.Child("url"), spec.URL, "exactly one of url or target must be set"))
+	case len(spec.URL) > 0:
+		errs = append(errs, validateURL(spec.URL, path.Child("url"))...)
+	case spec.Target == nil && len(spec.Endpoints) <= 0:
+		errs = append(errs, field.Required(path.Child("url"), "exactly one of url or target must be set"))
+	}
+
+	errs = append(errs, validateDataKeys(spec, spec.POSTDataKey, spec.PUTDataKey, path, path)...)
+
+	if len(spec.SecretName) <= 0 {
+		if spec.Authenticate {
+			errs = append(errs, field.Required(path.Child("secretName"), "must be set when authenticate is enabled"))
+		}
+		if spec.AuthenticateProxy {
+			errs = append(errs, field.Required(path.Child("secretName"), "must be set when authenticateProxy is enabled"))
+		}
+		if len(spec.TLS.ClientCertificateKey) > 0 {
+			errs = append(errs, field.Required(path.Child("secretName"), "must be set when tls.clientCertificateKey is set"))
+		}
+	}
+
+	if p := spec.TLS.Protocol; len(p) > 0 && !containsString(tlsProtocols, p) {
+		errs = append(errs, field.NotSupported(path.Child("tls", "protocol"), p, tlsProtocols))
+	}
+
+	if !spec.HTML.Enabled {
+		htmlPath := path.Child("html")
+		if len(spec.HTML.Table) > 0 {
+			errs = append(errs, field.Forbidden(htmlPath.Child("table"), "requires html.enabled"))
+		}
+		if len(spec.HTML.TD) > 0 {
+			errs = append(errs, field.Forbidden(htmlPath.Child("td"), "requires html.enabled"))
+		}
+		if len(spec.HTML.TR) > 0 {
+			errs = append(errs, field.Forbidden(htmlPath.Child("tr"), "requires html.enabled"))
+		}
+	}
+
+	if spec.Mode == ApacheBenchModeCapacitySearch && len(spec.Stages) > 0 {
+		errs = append(errs, field.Forbidden(path.Child("stages"), "cannot be used with the capacitySearch mode"))
+	}
+
+	if w := spec.Warmup; w != nil && w.TimeLimit <= 0 && w.Concurrency > 1 && w.Concurrency > w.Requests {
+		errs = append(errs, field.Invalid(path.Child("warmup", "concurrency"), w.Concurrency,
+			"cannot be greater than warmup.requests unless warmup.timeLimit is set"))
+	}
+
+	if spec.Output != nil && spec.Output.Pushgateway != nil {
+		labelsPath := path.Child("output", "pushgateway", "groupingLabels")
+		for key := range spec.Output.Pushgateway.GroupingLabels {
+			if containsString(ReservedPushgatewayLabels, key) {
+				errs = append(errs, field.Forbidden(labelsPath.Key(key), "is set by the operator"))
+			}
+		}
+	}
+
+	errs = append(errs, validateEndpoints(spec, path)...)
+	return errs
+}
+
+// validateDataKeys will return the errors for the given POST and PUT data keys at the given key path, which are read
+// from the ConfigMap named in the given spec at the given spec path.
+func validateDataKeys(spec *ApacheBenchSpec, postDataKey string, putDataKey string, specPath *field.Path, keyPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+
+	if len(postDataKey) > 0 && len(putDataKey) > 0 {
+		errs = append(errs, field.Forbidden(keyPath.Child("putDataKey"), "cannot be set with postDataKey"))
+	}
+
+	if len(spec.ConfigMapName) <= 0 {
+		if len(postDataKey) > 0 {
+			errs = append(errs, field.Required(specPath.Child("configMapName"),
+				"must be set when "+keyPath.Child("postDataKey").String()+" is set"))
+		}
+		if len(putDataKey) > 0 {
+			errs = append(errs, field.Required(specPath.Child("configMapName"),
+				"must be set when "+keyPath.Child("putDataKey").String()+" is set"))
+		}
+	}
+
+	return errs
+}
+
+// validateEndpoints will return the errors for the endpoints in the given spec at the given path.
+func validateEndpoints(spec *ApacheBenchSpec, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(spec.Endpoints) <= 0 {
+		return errs
+	}
+
+	if len(spec.Stages) > 0 || spec.Mode == ApacheBenchModeCapacitySearch {
+		errs = append(errs, field.Forbidden(path.Child("endpoints"),
+			"cannot be used with stages or the capacitySearch mode"))
+	}
+
+	names := make(map[string]bool)
+	for i, endpoint := range spec.Endpoints {
+		endpointPath := path.Child("endpoints").Index(i)
+
+		switch {
+		case len(endpoint.Name) <= 0:
+			errs = append(errs, field.Required(endpointPath.Child("name"), "a name must be set for each endpoint"))
+		case names[endpoint.Name]:
+			errs = append(errs, field.Duplicate(endpointPath.Child("name"), endpoint.Name))
+		}
+		names[endpoint.Name] = true
+
+		switch {
+		case len(endpoint.URL) <= 0:
+			errs = append(errs, field.Required(endpointPath.Child("url"), "a url must be set for each endpoint"))
+		case strings.HasPrefix(endpoint.URL, "/"):
+			if len(spec.URL) <= 0 && spec.Target == nil {
+				errs = append(errs, field.Invalid(endpointPath.Child("url"), endpoint.URL,
+					"a url or target must be set for the benchmark when the endpoint url is a path"))
+			}
+		default:
+			errs = append(errs, validateURL(endpoint.URL, endpointPath.Child("url"))...)
+		}
+
+		errs = append(errs, validateDataKeys(spec, endpoint.POSTDataKey, endpoint.PUTDataKey, path, endpointPath)...)
+	}
+
+	return errs
+}
+
+// validateURL will return an error if the given value at the given path is not an absolute HTTP or HTTPS URL with a
+// path, as ab rejects a URL without one.
+func validateURL(value string, path *field.Path) field.ErrorList {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) <= 0 {
+		return field.ErrorList{field.Invalid(path, value, "must be an absolute http or https URL")}
+	}
+	if len(u.Path) <= 0 {
+		u.Path = "/"
+		return field.ErrorList{field.Invalid(path, value, "must have a path, eg. "+u.String())}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+func TestValidateApacheBenchSpec(t *testing.T) {
+	tests := []struct {
+		name string
+		spec ApacheBenchSpec
+		want []string
+	}{
+		{
+			name: "valid",
+			spec: ApacheBenchSpec{URL: "http://example.com/"},
+		},
+		{
+			name: "url without a path",
+			spec: ApacheBenchSpec{URL: "http://example.com"},
+			want: []string{"spec.url"},
+		},
+		{
+			name: "url without a scheme",
+			spec: ApacheBenchSpec{URL: "example.com/"},
+			want: []string{"spec.url"},
+		},
+		{
+			name: "endpoint url without a path",
+			spec: ApacheBenchSpec{Endpoints: []ApacheBenchEndpointSpec{
+				{Name: "home", URL: "https://example.com/"},
+				{Name: "search", URL: "https://search.example.com?q=x"},
+			}},
+			want: []string{"spec.endpoints[1].url"},
+		},
+		{
+			name: "url and target",
+			spec: ApacheBenchSpec{URL: "http://example.com/", Target: &ApacheBenchTargetSpec{}},
+			want: []string{"spec.url"},
+		},
+		{
+			name: "warm-up concurrency within requests",
+			spec: ApacheBenchSpec{URL: "http://example.com/", Warmup: &ApacheBenchWarmupSpec{Concurrency: 10, Requests: 10}},
+		},
+		{
+			name: "warm-up concurrency greater than requests",
+			spec: ApacheBenchSpec{URL: "http://example.com/", Warmup: &ApacheBenchWarmupSpec{Concurrency: 20, Requests: 10}},
+			want: []string{"spec.warmup.concurrency"},
+		},
+		{
+			name: "warm-up concurrency with a time limit",
+			spec: ApacheBenchSpec{URL: "http://example.com/", Warmup: &ApacheBenchWarmupSpec{Concurrency: 20, TimeLimit: 10}},
+		},
+		{
+			name: "pushgateway grouping labels",
+			spec: ApacheBenchSpec{URL: "http://example.com/", Output: &ApacheBenchOutputSpec{
+				Pushgateway: &ApacheBenchPushgatewaySpec{URL: "http://pushgateway:9091", GroupingLabels: map[string]string{"env": "ci"}},
+			}},
+		},
+		{
+			name: "reserved pushgateway grouping label",
+			spec: ApacheBenchSpec{URL: "http://example.com/", Output: &ApacheBenchOutputSpec{
+				Pushgateway: &ApacheBenchPushgatewaySpec{URL: "http://pushgateway:9091", GroupingLabels: map[string]string{"run": "1"}},
+			}},
+			want: []string{"spec.output.pushgateway.groupingLabels[run]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateApacheBenchSpec(&tt.spec, field.NewPath("spec"))
+			if len(errs) != len(tt.want) {
+				t.Fatalf("validateApacheBenchSpec() = %v, want errors for %v", errs, tt.want)
+			}
+			for i, err := range errs {
+				if err.Field != tt.want[i] {
+					t.Errorf("validateApacheBenchSpec() error %d is for %s, want %s", i, err.Field, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ApacheBenchSuiteBenchmarkStatus defines the observed state of a single ApacheBench in the suite.
+type ApacheBenchSuiteBenchmarkStatus struct {
+	// Aggregate contains the results from all of the Job Pods for the benchmark combined, once it has completed.
+	Aggregate *ApacheBenchSummary `json:"aggregate,omitempty"`
+
+	// Concurrency is the number of multiple requests to perform at a time for the benchmark.
+	Concurrency uint32 `json:"concurrency,omitempty"`
+
+	// KeepAlive is true when the HTTP KeepAlive feature is enabled for the benchmark.
+	KeepAlive bool `json:"keepAlive"`
+
+	// Name is the name of the ApacheBench.
+	Name string `json:"name"`
+
+	// Phase is a simple, high-level summary of where the benchmark is in its lifecycle.
+	// See the Phase property on the ApacheBenchStatus for the possible values.
+	Phase string `json:"phase"`
+
+	// ThresholdBreaches describes each of the thresholds that were breached by the benchmark.
+	ThresholdBreaches []string `json:"thresholdBreaches,omitempty"`
+
+	// URL is the HTTP endpoint to benchmark.
+	URL string `json:"url,omitempty"`
+
+	// Verdict is the result of comparing the benchmark against the thresholds (Passed or Failed).
+	Verdict string `json:"verdict,omitempty"`
+}
+
+// ApacheBenchSuiteMatrixSpec defines the values that are combined to create each ApacheBench in the suite. An
+// ApacheBench is created for every combination of the values, and the value from the template is used for a property
+// without any values.
+type ApacheBenchSuiteMatrixSpec struct {
+	// Concurrency is a list of the number of multiple requests to perform at a time.
+	Concurrency []uint32 `json:"concurrency,omitempty"`
+
+	// KeepAlive is a list of values for the HTTP KeepAlive feature, eg. [false, true].
+	KeepAlive []bool `json:"keepAlive,omitempty"`
+
+	// URLs is a list of HTTP endpoints to benchmark.
+	URLs []string `json:"urls,omitempty"`
+}
+
+// ApacheBenchSuiteSpec defines the desired state of ApacheBenchSuite
+type ApacheBenchSuiteSpec struct {
+	// Matrix defines the values that are combined to create each ApacheBench in the suite.
+	Matrix ApacheBenchSuiteMatrixSpec `json:"matrix,omitempty"`
+
+	// MaxParallel is the maximum number of benchmarks in the suite that run at once. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	MaxParallel *int32 `json:"maxParallel,omitempty"`
+
+	// Template is the spec for each ApacheBench in the suite, before the values from the matrix are applied.
+	Template ApacheBenchSpec `json:"template"`
+}
+
+// ApacheBenchSuiteStatus defines the observed state of ApacheBenchSuite
+type ApacheBenchSuiteStatus struct {
+	// Benchmarks contains the state of each ApacheBench in the suite, in order.
+	Benchmarks []ApacheBenchSuiteBenchmarkStatus `json:"benchmarks,omitempty"`
+
+	// Completed is the number of benchmarks in the suite that have completed or failed.
+	Completed int32 `json:"completed"`
+
+	// Errors contains any errors that prevented the benchmarks in the suite from being created.
+	Errors []string `json:"errors,omitempty"`
+
+	// Failed is the number of benchmarks in the suite that have failed.
+	Failed int32 `json:"failed"`
+
+	// ObservedGeneration is the generation of the suite that the benchmarks were created for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Phase is a simple, high-level summary of where the ApacheBenchSuite is in its lifecycle.
+	// There are four possible phase values:
+	// Pending: None of the benchmarks in the suite have started.
+	// Running: At least one benchmark in the suite has not yet completed.
+	// Complete: All of the benchmarks in the suite have completed successfully.
+	// Failed: All of the benchmarks in the suite have finished, and at least one has failed.
+	Phase string `json:"phase"`
+
+	// Running is the number of benchmarks in the suite that are currently running.
+	Running int32 `json:"running"`
+
+	// Total is the number of benchmarks in the suite.
+	Total int32 `json:"total"`
+
+	// Verdict is the result of comparing every benchmark in the suite against the thresholds (Passed or Failed).
+	// The suite passes when every benchmark completes without breaching its thresholds. Not set while any benchmark
+	// is in progress.
+	Verdict string `json:"verdict,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ApacheBenchSuite is the Schema for the apachebenchsuites API
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:path=apachebenchsuites,scope=Namespaced
+type ApacheBenchSuite struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ApacheBenchSuiteSpec   `json:"spec,omitempty"`
+	Status ApacheBenchSuiteStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// ApacheBenchSuiteList contains a list of ApacheBenchSuite
+type ApacheBenchSuiteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ApacheBenchSuite `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ApacheBenchSuite{}, &ApacheBenchSuiteList{})
+}
+
+// AddError will add the given error message to the status, if not already present.
+func (s *ApacheBenchSuiteStatus) AddError(msg string) {
+	s.Errors = appendError(s.Errors, msg)
+}
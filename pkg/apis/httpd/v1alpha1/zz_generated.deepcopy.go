@@ -6,7 +6,10 @@ package v1alpha1
 
 import (
 	v1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	intstr "k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -37,6 +40,221 @@ func (in *ApacheBench) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchBaselineSpec) DeepCopyInto(out *ApacheBenchBaselineSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchBaselineSpec.
+func (in *ApacheBenchBaselineSpec) DeepCopy() *ApacheBenchBaselineSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchBaselineSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchCapacityIteration) DeepCopyInto(out *ApacheBenchCapacityIteration) {
+	*out = *in
+	if in.Breaches != nil {
+		in, out := &in.Breaches, &out.Breaches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]ApacheBenchResultsReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchCapacityIteration.
+func (in *ApacheBenchCapacityIteration) DeepCopy() *ApacheBenchCapacityIteration {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchCapacityIteration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchCapacityResult) DeepCopyInto(out *ApacheBenchCapacityResult) {
+	*out = *in
+	if in.Iterations != nil {
+		in, out := &in.Iterations, &out.Iterations
+		*out = make([]ApacheBenchCapacityIteration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchCapacityResult.
+func (in *ApacheBenchCapacityResult) DeepCopy() *ApacheBenchCapacityResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchCapacityResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchCapacitySearchSpec) DeepCopyInto(out *ApacheBenchCapacitySearchSpec) {
+	*out = *in
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = new(ApacheBenchThresholdsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchCapacitySearchSpec.
+func (in *ApacheBenchCapacitySearchSpec) DeepCopy() *ApacheBenchCapacitySearchSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchCapacitySearchSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchComparison) DeepCopyInto(out *ApacheBenchComparison) {
+	*out = *in
+	if in.PercentilesDelta != nil {
+		in, out := &in.PercentilesDelta, &out.PercentilesDelta
+		*out = new(ApacheBenchPercentiles)
+		**out = **in
+	}
+	if in.Regressions != nil {
+		in, out := &in.Regressions, &out.Regressions
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchComparison.
+func (in *ApacheBenchComparison) DeepCopy() *ApacheBenchComparison {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchComparison)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchCondition) DeepCopyInto(out *ApacheBenchCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchCondition.
+func (in *ApacheBenchCondition) DeepCopy() *ApacheBenchCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchConnectionTimes) DeepCopyInto(out *ApacheBenchConnectionTimes) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchConnectionTimes.
+func (in *ApacheBenchConnectionTimes) DeepCopy() *ApacheBenchConnectionTimes {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchConnectionTimes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchConnectionTimesSummary) DeepCopyInto(out *ApacheBenchConnectionTimesSummary) {
+	*out = *in
+	out.Connect = in.Connect
+	out.Processing = in.Processing
+	out.Total = in.Total
+	out.Waiting = in.Waiting
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchConnectionTimesSummary.
+func (in *ApacheBenchConnectionTimesSummary) DeepCopy() *ApacheBenchConnectionTimesSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchConnectionTimesSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchEndpointResult) DeepCopyInto(out *ApacheBenchEndpointResult) {
+	*out = *in
+	if in.Aggregate != nil {
+		in, out := &in.Aggregate, &out.Aggregate
+		*out = new(ApacheBenchSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchEndpointResult.
+func (in *ApacheBenchEndpointResult) DeepCopy() *ApacheBenchEndpointResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchEndpointResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchEndpointSpec) DeepCopyInto(out *ApacheBenchEndpointSpec) {
+	*out = *in
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchEndpointSpec.
+func (in *ApacheBenchEndpointSpec) DeepCopy() *ApacheBenchEndpointSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchEndpointSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApacheBenchHTMLSpec) DeepCopyInto(out *ApacheBenchHTMLSpec) {
 	*out = *in
@@ -53,6 +271,32 @@ func (in *ApacheBenchHTMLSpec) DeepCopy() *ApacheBenchHTMLSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchHTTPProbeSpec) DeepCopyInto(out *ApacheBenchHTTPProbeSpec) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchHTTPProbeSpec.
+func (in *ApacheBenchHTTPProbeSpec) DeepCopy() *ApacheBenchHTTPProbeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchHTTPProbeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ApacheBenchList) DeepCopyInto(out *ApacheBenchList) {
 	*out = *in
@@ -87,80 +331,784 @@ func (in *ApacheBenchList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ApacheBenchSpec) DeepCopyInto(out *ApacheBenchSpec) {
+func (in *ApacheBenchOutputSpec) DeepCopyInto(out *ApacheBenchOutputSpec) {
 	*out = *in
-	if in.Cookies != nil {
-		in, out := &in.Cookies, &out.Cookies
-		*out = make(map[string]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.Pushgateway != nil {
+		in, out := &in.Pushgateway, &out.Pushgateway
+		*out = new(ApacheBenchPushgatewaySpec)
+		(*in).DeepCopyInto(*out)
 	}
-	if in.Headers != nil {
-		in, out := &in.Headers, &out.Headers
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(ApacheBenchS3Spec)
+		**out = **in
+	}
+	if in.VolumeClaim != nil {
+		in, out := &in.VolumeClaim, &out.VolumeClaim
+		*out = new(ApacheBenchVolumeClaimSpec)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchOutputSpec.
+func (in *ApacheBenchOutputSpec) DeepCopy() *ApacheBenchOutputSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchOutputSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchPercentiles) DeepCopyInto(out *ApacheBenchPercentiles) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchPercentiles.
+func (in *ApacheBenchPercentiles) DeepCopy() *ApacheBenchPercentiles {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchPercentiles)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchPushgatewaySpec) DeepCopyInto(out *ApacheBenchPushgatewaySpec) {
+	*out = *in
+	if in.GroupingLabels != nil {
+		in, out := &in.GroupingLabels, &out.GroupingLabels
 		*out = make(map[string]string, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
-	out.HTML = in.HTML
-	if in.Job != nil {
-		in, out := &in.Job, &out.Job
-		*out = new(v1.JobSpec)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchPushgatewaySpec.
+func (in *ApacheBenchPushgatewaySpec) DeepCopy() *ApacheBenchPushgatewaySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchPushgatewaySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchReadinessSpec) DeepCopyInto(out *ApacheBenchReadinessSpec) {
+	*out = *in
+	if in.HTTPGet != nil {
+		in, out := &in.HTTPGet, &out.HTTPGet
+		*out = new(ApacheBenchHTTPProbeSpec)
 		(*in).DeepCopyInto(*out)
 	}
-	out.TLS = in.TLS
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSpec.
-func (in *ApacheBenchSpec) DeepCopy() *ApacheBenchSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchReadinessSpec.
+func (in *ApacheBenchReadinessSpec) DeepCopy() *ApacheBenchReadinessSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ApacheBenchSpec)
+	out := new(ApacheBenchReadinessSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ApacheBenchStatus) DeepCopyInto(out *ApacheBenchStatus) {
+func (in *ApacheBenchResultsReference) DeepCopyInto(out *ApacheBenchResultsReference) {
 	*out = *in
-	if in.Errors != nil {
-		in, out := &in.Errors, &out.Errors
+	if in.ConfigMaps != nil {
+		in, out := &in.ConfigMaps, &out.ConfigMaps
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
-	if in.Results != nil {
-		in, out := &in.Results, &out.Results
+	if in.CSVConfigMaps != nil {
+		in, out := &in.CSVConfigMaps, &out.CSVConfigMaps
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.GnuplotConfigMaps != nil {
+		in, out := &in.GnuplotConfigMaps, &out.GnuplotConfigMaps
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	return
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchStatus.
-func (in *ApacheBenchStatus) DeepCopy() *ApacheBenchStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchResultsReference.
+func (in *ApacheBenchResultsReference) DeepCopy() *ApacheBenchResultsReference {
 	if in == nil {
 		return nil
 	}
-	out := new(ApacheBenchStatus)
+	out := new(ApacheBenchResultsReference)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ApacheBenchTLSSpec) DeepCopyInto(out *ApacheBenchTLSSpec) {
+func (in *ApacheBenchRun) DeepCopyInto(out *ApacheBenchRun) {
 	*out = *in
-	return
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchTLSSpec.
-func (in *ApacheBenchTLSSpec) DeepCopy() *ApacheBenchTLSSpec {
-	if in == nil {
-		return nil
+	if in.Aggregate != nil {
+		in, out := &in.Aggregate, &out.Aggregate
+		*out = new(ApacheBenchSummary)
+		(*in).DeepCopyInto(*out)
 	}
-	out := new(ApacheBenchTLSSpec)
+	if in.ArchivedObjects != nil {
+		in, out := &in.ArchivedObjects, &out.ArchivedObjects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ArchiveTime != nil {
+		in, out := &in.ArchiveTime, &out.ArchiveTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(ApacheBenchCapacityResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]ApacheBenchEndpointResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PushTime != nil {
+		in, out := &in.PushTime, &out.PushTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]ApacheBenchResultsReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ScheduledTime != nil {
+		in, out := &in.ScheduledTime, &out.ScheduledTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Stages != nil {
+		in, out := &in.Stages, &out.Stages
+		*out = make([]ApacheBenchStageResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchRun.
+func (in *ApacheBenchRun) DeepCopy() *ApacheBenchRun {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchS3Spec) DeepCopyInto(out *ApacheBenchS3Spec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchS3Spec.
+func (in *ApacheBenchS3Spec) DeepCopy() *ApacheBenchS3Spec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchS3Spec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchSpec) DeepCopyInto(out *ApacheBenchSpec) {
+	*out = *in
+	if in.Baseline != nil {
+		in, out := &in.Baseline, &out.Baseline
+		*out = new(ApacheBenchBaselineSpec)
+		**out = **in
+	}
+	if in.CapacitySearch != nil {
+		in, out := &in.CapacitySearch, &out.CapacitySearch
+		*out = new(ApacheBenchCapacitySearchSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cookies != nil {
+		in, out := &in.Cookies, &out.Cookies
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CookiesFrom != nil {
+		in, out := &in.CookiesFrom, &out.CookiesFrom
+		*out = make([]ApacheBenchValueFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]ApacheBenchEndpointSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HeadersFrom != nil {
+		in, out := &in.HeadersFrom, &out.HeadersFrom
+		*out = make([]ApacheBenchValueFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.HTML = in.HTML
+	if in.Job != nil {
+		in, out := &in.Job, &out.Job
+		*out = new(v1.JobSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Output != nil {
+		in, out := &in.Output, &out.Output
+		*out = new(ApacheBenchOutputSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Readiness != nil {
+		in, out := &in.Readiness, &out.Readiness
+		*out = new(ApacheBenchReadinessSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RunHistoryLimit != nil {
+		in, out := &in.RunHistoryLimit, &out.RunHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Stages != nil {
+		in, out := &in.Stages, &out.Stages
+		*out = make([]ApacheBenchStageSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Target != nil {
+		in, out := &in.Target, &out.Target
+		*out = new(ApacheBenchTargetSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Thresholds != nil {
+		in, out := &in.Thresholds, &out.Thresholds
+		*out = new(ApacheBenchThresholdsSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	out.TLS = in.TLS
+	if in.Warmup != nil {
+		in, out := &in.Warmup, &out.Warmup
+		*out = new(ApacheBenchWarmupSpec)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSpec.
+func (in *ApacheBenchSpec) DeepCopy() *ApacheBenchSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchStageResult) DeepCopyInto(out *ApacheBenchStageResult) {
+	*out = *in
+	if in.Aggregate != nil {
+		in, out := &in.Aggregate, &out.Aggregate
+		*out = new(ApacheBenchSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchStageResult.
+func (in *ApacheBenchStageResult) DeepCopy() *ApacheBenchStageResult {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchStageResult)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchStageSpec) DeepCopyInto(out *ApacheBenchStageSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchStageSpec.
+func (in *ApacheBenchStageSpec) DeepCopy() *ApacheBenchStageSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchStageSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchStatus) DeepCopyInto(out *ApacheBenchStatus) {
+	*out = *in
+	if in.Aggregate != nil {
+		in, out := &in.Aggregate, &out.Aggregate
+		*out = new(ApacheBenchSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(ApacheBenchCapacityResult)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Comparison != nil {
+		in, out := &in.Comparison, &out.Comparison
+		*out = new(ApacheBenchComparison)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ApacheBenchCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]ApacheBenchEndpointResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Results != nil {
+		in, out := &in.Results, &out.Results
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ResultsRefs != nil {
+		in, out := &in.ResultsRefs, &out.ResultsRefs
+		*out = make([]ApacheBenchResultsReference, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Runs != nil {
+		in, out := &in.Runs, &out.Runs
+		*out = make([]ApacheBenchRun, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Stages != nil {
+		in, out := &in.Stages, &out.Stages
+		*out = make([]ApacheBenchStageResult, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Summary != nil {
+		in, out := &in.Summary, &out.Summary
+		*out = make([]ApacheBenchSummary, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ThresholdBreaches != nil {
+		in, out := &in.ThresholdBreaches, &out.ThresholdBreaches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchStatus.
+func (in *ApacheBenchStatus) DeepCopy() *ApacheBenchStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchSuite) DeepCopyInto(out *ApacheBenchSuite) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSuite.
+func (in *ApacheBenchSuite) DeepCopy() *ApacheBenchSuite {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchSuite)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApacheBenchSuite) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchSuiteBenchmarkStatus) DeepCopyInto(out *ApacheBenchSuiteBenchmarkStatus) {
+	*out = *in
+	if in.Aggregate != nil {
+		in, out := &in.Aggregate, &out.Aggregate
+		*out = new(ApacheBenchSummary)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ThresholdBreaches != nil {
+		in, out := &in.ThresholdBreaches, &out.ThresholdBreaches
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSuiteBenchmarkStatus.
+func (in *ApacheBenchSuiteBenchmarkStatus) DeepCopy() *ApacheBenchSuiteBenchmarkStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchSuiteBenchmarkStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchSuiteList) DeepCopyInto(out *ApacheBenchSuiteList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ApacheBenchSuite, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSuiteList.
+func (in *ApacheBenchSuiteList) DeepCopy() *ApacheBenchSuiteList {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchSuiteList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ApacheBenchSuiteList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchSuiteMatrixSpec) DeepCopyInto(out *ApacheBenchSuiteMatrixSpec) {
+	*out = *in
+	if in.Concurrency != nil {
+		in, out := &in.Concurrency, &out.Concurrency
+		*out = make([]uint32, len(*in))
+		copy(*out, *in)
+	}
+	if in.KeepAlive != nil {
+		in, out := &in.KeepAlive, &out.KeepAlive
+		*out = make([]bool, len(*in))
+		copy(*out, *in)
+	}
+	if in.URLs != nil {
+		in, out := &in.URLs, &out.URLs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSuiteMatrixSpec.
+func (in *ApacheBenchSuiteMatrixSpec) DeepCopy() *ApacheBenchSuiteMatrixSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchSuiteMatrixSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchSuiteSpec) DeepCopyInto(out *ApacheBenchSuiteSpec) {
+	*out = *in
+	in.Matrix.DeepCopyInto(&out.Matrix)
+	if in.MaxParallel != nil {
+		in, out := &in.MaxParallel, &out.MaxParallel
+		*out = new(int32)
+		**out = **in
+	}
+	in.Template.DeepCopyInto(&out.Template)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSuiteSpec.
+func (in *ApacheBenchSuiteSpec) DeepCopy() *ApacheBenchSuiteSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchSuiteSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchSuiteStatus) DeepCopyInto(out *ApacheBenchSuiteStatus) {
+	*out = *in
+	if in.Benchmarks != nil {
+		in, out := &in.Benchmarks, &out.Benchmarks
+		*out = make([]ApacheBenchSuiteBenchmarkStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Errors != nil {
+		in, out := &in.Errors, &out.Errors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSuiteStatus.
+func (in *ApacheBenchSuiteStatus) DeepCopy() *ApacheBenchSuiteStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchSuiteStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchSummary) DeepCopyInto(out *ApacheBenchSummary) {
+	*out = *in
+	if in.ConnectionTimes != nil {
+		in, out := &in.ConnectionTimes, &out.ConnectionTimes
+		*out = new(ApacheBenchConnectionTimesSummary)
+		**out = **in
+	}
+	if in.Percentiles != nil {
+		in, out := &in.Percentiles, &out.Percentiles
+		*out = new(ApacheBenchPercentiles)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchSummary.
+func (in *ApacheBenchSummary) DeepCopy() *ApacheBenchSummary {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchSummary)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchTLSSpec) DeepCopyInto(out *ApacheBenchTLSSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchTLSSpec.
+func (in *ApacheBenchTLSSpec) DeepCopy() *ApacheBenchTLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchTLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchTargetSpec) DeepCopyInto(out *ApacheBenchTargetSpec) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchTargetSpec.
+func (in *ApacheBenchTargetSpec) DeepCopy() *ApacheBenchTargetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchTargetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchThresholdsSpec) DeepCopyInto(out *ApacheBenchThresholdsSpec) {
+	*out = *in
+	if in.MaxFailedRequestRatio != nil {
+		in, out := &in.MaxFailedRequestRatio, &out.MaxFailedRequestRatio
+		*out = new(float64)
+		**out = **in
+	}
+	if in.MaxMeanLatency != nil {
+		in, out := &in.MaxMeanLatency, &out.MaxMeanLatency
+		*out = new(float64)
+		**out = **in
+	}
+	if in.MaxNon2xxResponses != nil {
+		in, out := &in.MaxNon2xxResponses, &out.MaxNon2xxResponses
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxP95Latency != nil {
+		in, out := &in.MaxP95Latency, &out.MaxP95Latency
+		*out = new(float64)
+		**out = **in
+	}
+	if in.MaxP99Latency != nil {
+		in, out := &in.MaxP99Latency, &out.MaxP99Latency
+		*out = new(float64)
+		**out = **in
+	}
+	if in.MinRequestsPerSecond != nil {
+		in, out := &in.MinRequestsPerSecond, &out.MinRequestsPerSecond
+		*out = new(float64)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchThresholdsSpec.
+func (in *ApacheBenchThresholdsSpec) DeepCopy() *ApacheBenchThresholdsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchThresholdsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchValueFromSource) DeepCopyInto(out *ApacheBenchValueFromSource) {
+	*out = *in
+	if in.ConfigMapKeyRef != nil {
+		in, out := &in.ConfigMapKeyRef, &out.ConfigMapKeyRef
+		*out = new(corev1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecretKeyRef != nil {
+		in, out := &in.SecretKeyRef, &out.SecretKeyRef
+		*out = new(corev1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchValueFromSource.
+func (in *ApacheBenchValueFromSource) DeepCopy() *ApacheBenchValueFromSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchValueFromSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchVolumeClaimSpec) DeepCopyInto(out *ApacheBenchVolumeClaimSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchVolumeClaimSpec.
+func (in *ApacheBenchVolumeClaimSpec) DeepCopy() *ApacheBenchVolumeClaimSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchVolumeClaimSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ApacheBenchWarmupSpec) DeepCopyInto(out *ApacheBenchWarmupSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ApacheBenchWarmupSpec.
+func (in *ApacheBenchWarmupSpec) DeepCopy() *ApacheBenchWarmupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ApacheBenchWarmupSpec)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -0,0 +1,160 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"math"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+// aggregateConnectionTimes will combine the given connection times using the given weights.
+// The standard deviation is pooled, taking into account the difference between each mean and the combined mean.
+func aggregateConnectionTimes(times []v1a1.ApacheBenchConnectionTimes, weights []float64) v1a1.ApacheBenchConnectionTimes {
+	agg := v1a1.ApacheBenchConnectionTimes{}
+
+	for i, ct := range times {
+		if i == 0 || ct.Min < agg.Min {
+			agg.Min = ct.Min
+		}
+		if ct.Max > agg.Max {
+			agg.Max = ct.Max
+		}
+		agg.Mean += ct.Mean * weights[i]
+		agg.Median += ct.Median * weights[i]
+	}
+
+	variance := 0.0
+	for i, ct := range times {
+		variance += weights[i] * (ct.StdDev*ct.StdDev + (ct.Mean-agg.Mean)*(ct.Mean-agg.Mean))
+	}
+	agg.StdDev = math.Sqrt(variance)
+
+	return agg
+}
+
+// aggregatePercentiles will combine the given percentiles using the given weights.
+// Each percentile is the weighted mean of the given values, with the exception of the longest request.
+func aggregatePercentiles(percentiles []v1a1.ApacheBenchPercentiles, weights []float64) v1a1.ApacheBenchPercentiles {
+	agg := v1a1.ApacheBenchPercentiles{}
+
+	for i, p := range percentiles {
+		agg.P50 += p.P50 * weights[i]
+		agg.P66 += p.P66 * weights[i]
+		agg.P75 += p.P75 * weights[i]
+		agg.P80 += p.P80 * weights[i]
+		agg.P90 += p.P90 * weights[i]
+		agg.P95 += p.P95 * weights[i]
+		agg.P98 += p.P98 * weights[i]
+		agg.P99 += p.P99 * weights[i]
+		agg.P100 = math.Max(agg.P100, p.P100)
+	}
+
+	return agg
+}
+
+// aggregateSummaries will combine the given summaries into a single summary for all benchmark Job Pods.
+// Counts and throughput are summed, while latencies are weighted by the number of completed requests.
+func aggregateSummaries(summaries []v1a1.ApacheBenchSummary) *v1a1.ApacheBenchSummary {
+	if len(summaries) <= 0 {
+		return nil
+	}
+
+	agg := &v1a1.ApacheBenchSummary{
+		DocumentLength: summaries[0].DocumentLength,
+		DocumentPath:   summaries[0].DocumentPath,
+		ServerHostname: summaries[0].ServerHostname,
+		ServerPort:     summaries[0].ServerPort,
+		ServerSoftware: summaries[0].ServerSoftware,
+	}
+
+	for _, s := range summaries {
+		agg.CompleteRequests += s.CompleteRequests
+		agg.ConcurrencyLevel += s.ConcurrencyLevel
+		agg.FailedRequests += s.FailedRequests
+		agg.HTMLTransferred += s.HTMLTransferred
+		agg.KeepAliveRequests += s.KeepAliveRequests
+		agg.Non2xxResponses += s.Non2xxResponses
+		agg.RequestsPerSecond += s.RequestsPerSecond
+		agg.TimeTaken = math.Max(agg.TimeTaken, s.TimeTaken)
+		agg.TotalTransferred += s.TotalTransferred
+		agg.TransferRate += s.TransferRate
+		agg.WriteErrors += s.WriteErrors
+	}
+
+	weights := summaryWeights(summaries)
+	for i, s := range summaries {
+		agg.TimePerRequest += s.TimePerRequest * weights[i]
+	}
+
+	if agg.RequestsPerSecond > 0 {
+		agg.TimePerRequestAcrossConcurrency = 1000 / agg.RequestsPerSecond
+	}
+
+	connect := make([]v1a1.ApacheBenchConnectionTimes, 0)
+	processing := make([]v1a1.ApacheBenchConnectionTimes, 0)
+	waiting := make([]v1a1.ApacheBenchConnectionTimes, 0)
+	total := make([]v1a1.ApacheBenchConnectionTimes, 0)
+	percentiles := make([]v1a1.ApacheBenchPercentiles, 0)
+	for _, s := range summaries {
+		if s.ConnectionTimes != nil {
+			connect = append(connect, s.ConnectionTimes.Connect)
+			processing = append(processing, s.ConnectionTimes.Processing)
+			waiting = append(waiting, s.ConnectionTimes.Waiting)
+			total = append(total, s.ConnectionTimes.Total)
+		}
+		if s.Percentiles != nil {
+			percentiles = append(percentiles, *s.Percentiles)
+		}
+	}
+
+	// Only combine the tables when every summary reported them, otherwise the weights do not apply.
+	if len(total) == len(summaries) {
+		agg.ConnectionTimes = &v1a1.ApacheBenchConnectionTimesSummary{
+			Connect:    aggregateConnectionTimes(connect, weights),
+			Processing: aggregateConnectionTimes(processing, weights),
+			Waiting:    aggregateConnectionTimes(waiting, weights),
+			Total:      aggregateConnectionTimes(total, weights),
+		}
+	}
+
+	if len(percentiles) == len(summaries) {
+		p := aggregatePercentiles(percentiles, weights)
+		agg.Percentiles = &p
+	}
+
+	return agg
+}
+
+// summaryWeights will return the weight for each of the given summaries, based on the number of completed requests.
+// The summaries are weighted equally when no requests were completed.
+func summaryWeights(summaries []v1a1.ApacheBenchSummary) []float64 {
+	weights := make([]float64, len(summaries))
+
+	total := int64(0)
+	for _, s := range summaries {
+		total += s.CompleteRequests
+	}
+
+	for i, s := range summaries {
+		if total > 0 {
+			weights[i] = float64(s.CompleteRequests) / float64(total)
+		} else {
+			weights[i] = 1 / float64(len(summaries))
+		}
+	}
+
+	return weights
+}
@@ -16,6 +16,7 @@ package apachebench
 
 import (
 	"context"
+	"time"
 
 	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -50,7 +51,7 @@ func add(mgr manager.Manager, r reconcile.Reconciler) error {
 	}
 
 	// Register watches for all controller resources
-	if err := watchResources(c); err != nil {
+	if err := watchResources(c, mgr.GetClient()); err != nil {
 		return err
 	}
 
@@ -85,17 +86,39 @@ func (r *ReconcileApacheBench) Reconcile(request reconcile.Request) (reconcile.R
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
 			// Return and don't requeue
+			deleteMetrics(request.NamespacedName)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, err
 	}
 
-	if err := r.reconcileResources(ab); err != nil {
-		// Error reconciling ApacheBench sub-resources - requeue the request.
-		return reconcile.Result{}, err
+	err = r.reconcileResources(ab)
+
+	// Export the results for the completed runs, also when an output failed and the request is requeued.
+	updateMetrics(ab)
+
+	if err != nil {
+		if !isJobCreationError(err) {
+			// Error reconciling ApacheBench sub-resources - requeue the request.
+			return reconcile.Result{}, err
+		}
+		// The run has been marked as failed, the Job is created once the spec or a referenced object changes.
+	}
+
+	result := reconcile.Result{}
+
+	// Requeue the request while the current run is waiting for the target or readiness checks.
+	if isWaitingForTarget(ab) || isWaitingForProbe(ab) {
+		result.RequeueAfter = getReadinessInterval(ab)
+	}
+
+	// Requeue the request for the next scheduled run, if any.
+	if next := getNextScheduleTime(ab, time.Now()); !next.IsZero() {
+		if result.RequeueAfter <= 0 || time.Until(next) < result.RequeueAfter {
+			result.RequeueAfter = time.Until(next)
+		}
 	}
 
-	// Return and don't requeue
-	return reconcile.Result{}, nil
+	return result, nil
 }
@@ -0,0 +1,183 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// s3AccessKeyIDKey is the key in the credentials Secret that contains the access key ID.
+	s3AccessKeyIDKey = "accessKeyID"
+
+	// s3SecretAccessKeyKey is the key in the credentials Secret that contains the secret access key.
+	s3SecretAccessKeyKey = "secretAccessKey"
+
+	// s3SessionTokenKey is the optional key in the credentials Secret that contains the session token.
+	s3SessionTokenKey = "sessionToken"
+)
+
+// archiveFile is a single file of results for a run to upload to object storage.
+type archiveFile struct {
+	contentType string
+	data        []byte
+	name        string
+}
+
+// archivedResults is the parsed results for a run, as uploaded to object storage.
+type archivedResults struct {
+	Name      string                    `json:"name"`
+	Namespace string                    `json:"namespace"`
+	Run       v1a1.ApacheBenchRun       `json:"run"`
+	Summary   []v1a1.ApacheBenchSummary `json:"summary,omitempty"`
+}
+
+// getArchiveFiles will return the files of results to upload for the given run of the given ApacheBench. This
+// includes the output, CSV and gnuplot files from each Job Pod and the parsed results.
+func (r *ReconcileApacheBench) getArchiveFiles(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) ([]archiveFile, error) {
+	files := make([]archiveFile, 0)
+
+	for _, ref := range run.Results {
+		output := archiveFile{contentType: "text/plain; charset=utf-8", name: ref.Pod + ".txt"}
+		if cr.Spec.HTML.Enabled {
+			output.contentType, output.name = "text/html; charset=utf-8", ref.Pod+".html"
+		}
+
+		podFiles := []struct {
+			file       archiveFile
+			configMaps []string
+		}{
+			{output, ref.ConfigMaps},
+			{archiveFile{contentType: "text/csv", name: ref.Pod + ".csv"}, ref.CSVConfigMaps},
+			{archiveFile{contentType: "text/tab-separated-values", name: ref.Pod + ".tsv"}, ref.GnuplotConfigMaps},
+		}
+		for _, pf := range podFiles {
+			if len(pf.configMaps) <= 0 {
+				continue
+			}
+
+			data, err := r.getStoredResults(cr.Namespace, pf.configMaps)
+			if err != nil {
+				return nil, err
+			}
+			pf.file.data = data
+			files = append(files, pf.file)
+		}
+	}
+
+	results, err := json.MarshalIndent(archivedResults{
+		Name:      cr.Name,
+		Namespace: cr.Namespace,
+		Run:       *run,
+		Summary:   cr.Status.Summary,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, archiveFile{contentType: "application/json", data: results, name: "results.json"})
+
+	return files, nil
+}
+
+// getArchiveKey will return the object key for the given file of results for the given run of the given ApacheBench.
+func getArchiveKey(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, name string) string {
+	return path.Join(cr.Spec.Output.S3.Prefix, cr.Namespace, cr.Name, strconv.Itoa(int(run.Number)), name)
+}
+
+// getS3Client will return a client for the object storage defined by the given ApacheBench, using the credentials
+// from the referenced Secret.
+func (r *ReconcileApacheBench) getS3Client(cr *v1a1.ApacheBench) (*s3Client, error) {
+	spec := cr.Spec.Output.S3
+
+	secret := &corev1.Secret{}
+	if err := r.fetchObject(cr.Namespace, spec.CredentialsSecret, secret); err != nil {
+		return nil, fmt.Errorf("unable to get secret '%s': %v", spec.CredentialsSecret, err)
+	}
+
+	for _, key := range []string{s3AccessKeyIDKey, s3SecretAccessKeyKey} {
+		if _, ok := secret.Data[key]; !ok {
+			return nil, fmt.Errorf("unable to locate key '%s' in secret '%s'", key, spec.CredentialsSecret)
+		}
+	}
+
+	return newS3Client(spec.Endpoint, spec.Region, string(secret.Data[s3AccessKeyIDKey]),
+		string(secret.Data[s3SecretAccessKeyKey]), string(secret.Data[s3SessionTokenKey])), nil
+}
+
+// reconcileArchive will upload the results for the current run of the given ApacheBench to object storage, once the
+// run has completed. The results are only uploaded once for each run. An error is returned if the upload fails, so
+// that the request is retried with backoff.
+func (r *ReconcileApacheBench) reconcileArchive(cr *v1a1.ApacheBench) error {
+	run := cr.GetCurrentRun()
+	if cr.Spec.Output == nil || cr.Spec.Output.S3 == nil || run == nil {
+		removeCondition(cr, v1a1.ApacheBenchConditionResultsArchived)
+		return nil
+	}
+
+	if run.ArchiveTime != nil {
+		return nil // Already archived
+	}
+
+	if !waitForRunCompletion(cr, run, v1a1.ApacheBenchConditionResultsArchived, "archive") {
+		return nil
+	}
+
+	if err := r.uploadResults(cr, run); err != nil {
+		setCondition(cr, v1a1.ApacheBenchConditionResultsArchived, corev1.ConditionFalse, "UploadFailed",
+			fmt.Sprintf("unable to upload results for run %d: %v", run.Number, err))
+		return err
+	}
+
+	now := metav1.Now()
+	run.ArchiveTime = &now
+	setCondition(cr, v1a1.ApacheBenchConditionResultsArchived, corev1.ConditionTrue, "Uploaded",
+		fmt.Sprintf("uploaded %d object(s) for run %d to bucket '%s'", len(run.ArchivedObjects), run.Number,
+			cr.Spec.Output.S3.Bucket))
+	return nil
+}
+
+// uploadResults will upload each file of results for the given run of the given ApacheBench to object storage, and
+// record the keys of the uploaded objects on the run.
+func (r *ReconcileApacheBench) uploadResults(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) error {
+	s3, err := r.getS3Client(cr)
+	if err != nil {
+		return err
+	}
+
+	files, err := r.getArchiveFiles(cr, run)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0)
+	for _, file := range files {
+		key := getArchiveKey(cr, run, file.name)
+		if err := s3.putObject(cr.Spec.Output.S3.Bucket, key, file.data, file.contentType); err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	run.ArchivedObjects = keys
+	return nil
+}
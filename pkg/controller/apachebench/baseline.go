@@ -0,0 +1,141 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"context"
+	"fmt"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// compareSummaries will compare the given summary with the given baseline summary. A regression is reported for any
+// drop in throughput, or rise in latency, that is greater than the given percentage of the baseline value.
+func compareSummaries(summary *v1a1.ApacheBenchSummary, baseline *v1a1.ApacheBenchSummary, maxRegression float64) *v1a1.ApacheBenchComparison {
+	comparison := &v1a1.ApacheBenchComparison{
+		MeanLatencyDelta:       percentDelta(summary.TimePerRequest, baseline.TimePerRequest),
+		RequestsPerSecondDelta: percentDelta(summary.RequestsPerSecond, baseline.RequestsPerSecond),
+	}
+
+	if -comparison.RequestsPerSecondDelta > maxRegression {
+		comparison.Regressions = append(comparison.Regressions, fmt.Sprintf(
+			"requests per second dropped by %.2f%% from %.2f to %.2f",
+			-comparison.RequestsPerSecondDelta, baseline.RequestsPerSecond, summary.RequestsPerSecond))
+	}
+
+	if comparison.MeanLatencyDelta > maxRegression {
+		comparison.Regressions = append(comparison.Regressions, fmt.Sprintf(
+			"mean latency rose by %.2f%% from %.3fms to %.3fms",
+			comparison.MeanLatencyDelta, baseline.TimePerRequest, summary.TimePerRequest))
+	}
+
+	if summary.Percentiles != nil && baseline.Percentiles != nil {
+		p, b := summary.Percentiles, baseline.Percentiles
+		comparison.PercentilesDelta = &v1a1.ApacheBenchPercentiles{
+			P50:  percentDelta(p.P50, b.P50),
+			P66:  percentDelta(p.P66, b.P66),
+			P75:  percentDelta(p.P75, b.P75),
+			P80:  percentDelta(p.P80, b.P80),
+			P90:  percentDelta(p.P90, b.P90),
+			P95:  percentDelta(p.P95, b.P95),
+			P98:  percentDelta(p.P98, b.P98),
+			P99:  percentDelta(p.P99, b.P99),
+			P100: percentDelta(p.P100, b.P100),
+		}
+
+		// The longest request is too noisy to be useful in detecting a regression, so it is only reported.
+		checks := []struct {
+			name     string
+			delta    float64
+			current  float64
+			baseline float64
+		}{
+			{"p50", comparison.PercentilesDelta.P50, p.P50, b.P50},
+			{"p90", comparison.PercentilesDelta.P90, p.P90, b.P90},
+			{"p95", comparison.PercentilesDelta.P95, p.P95, b.P95},
+			{"p99", comparison.PercentilesDelta.P99, p.P99, b.P99},
+		}
+		for _, c := range checks {
+			if c.delta > maxRegression {
+				comparison.Regressions = append(comparison.Regressions, fmt.Sprintf(
+					"%s latency rose by %.2f%% from %.3fms to %.3fms", c.name, c.delta, c.baseline, c.current))
+			}
+		}
+	}
+
+	comparison.Regressed = len(comparison.Regressions) > 0
+	return comparison
+}
+
+// getBaselineRun will return the run of the given ApacheBench to use as the baseline. The run with the given number is
+// returned, or the most recently completed run when number is zero. The run with the excluded number is never returned.
+func getBaselineRun(cr *v1a1.ApacheBench, number int32, excluded int32) *v1a1.ApacheBenchRun {
+	for i := len(cr.Status.Runs) - 1; i >= 0; i-- {
+		run := &cr.Status.Runs[i]
+		if run.Number == excluded || run.Phase != v1a1.ApacheBenchPhaseComplete || run.Aggregate == nil {
+			continue
+		}
+		if number <= 0 || run.Number == number {
+			return run
+		}
+	}
+	return nil
+}
+
+// percentDelta will return the change from the given baseline value as a percentage of the baseline value.
+func percentDelta(value float64, baseline float64) float64 {
+	if baseline == 0 {
+		return 0
+	}
+	return (value - baseline) / baseline * 100
+}
+
+// updateComparison will compare the results of the current run for the given ApacheBench with the baseline and update
+// the comparison in the status. The comparison is cleared until the current run completes. When the baseline is
+// another ApacheBench, the comparison is also updated whenever that ApacheBench changes, eg. once its next run completes.
+func (r *ReconcileApacheBench) updateComparison(cr *v1a1.ApacheBench) {
+	run := cr.GetCurrentRun()
+	if cr.Spec.Baseline == nil || run == nil || run.Phase != v1a1.ApacheBenchPhaseComplete || run.Aggregate == nil {
+		cr.Status.Comparison = nil
+		return
+	}
+
+	baseline := cr
+	excluded := run.Number
+	if len(cr.Spec.Baseline.Name) > 0 && cr.Spec.Baseline.Name != cr.Name {
+		baseline = &v1a1.ApacheBench{}
+		key := types.NamespacedName{Namespace: cr.Namespace, Name: cr.Spec.Baseline.Name}
+		if err := r.client.Get(context.TODO(), key, baseline); err != nil {
+			cr.Status.Comparison = nil
+			cr.Status.AddError(fmt.Sprintf("unable to get baseline %s: %v", key.Name, err))
+			return
+		}
+		excluded = 0
+	}
+
+	baselineRun := getBaselineRun(baseline, cr.Spec.Baseline.Run, excluded)
+	if baselineRun == nil {
+		log.Info("no completed baseline run found", "namespace", cr.Namespace, "name", cr.Name, "baseline", baseline.Name)
+		cr.Status.Comparison = nil
+		return
+	}
+
+	comparison := compareSummaries(run.Aggregate, baselineRun.Aggregate, cr.Spec.Baseline.MaxRegressionPercent)
+	comparison.Baseline = baseline.Name
+	comparison.BaselineRun = baselineRun.Number
+	cr.Status.Comparison = comparison
+}
@@ -0,0 +1,325 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"reflect"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// newBaselineRun will return a run with the given number and phase, with results at the given requests per second.
+func newBaselineRun(number int32, phase string, requestsPerSecond float64) v1a1.ApacheBenchRun {
+	run := v1a1.ApacheBenchRun{Number: number, Phase: phase}
+	if requestsPerSecond > 0 {
+		run.Aggregate = &v1a1.ApacheBenchSummary{RequestsPerSecond: requestsPerSecond, TimePerRequest: 1000 / requestsPerSecond}
+	}
+	return run
+}
+
+func TestPercentDelta(t *testing.T) {
+	tests := []struct {
+		value    float64
+		baseline float64
+		want     float64
+	}{
+		{110, 100, 10},
+		{90, 100, -10},
+		{100, 100, 0},
+		{0, 100, -100},
+		{50, 0, 0},
+		{0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := percentDelta(tt.value, tt.baseline); !floatsEqual(got, tt.want) {
+			t.Errorf("percentDelta(%v, %v) = %v, want %v", tt.value, tt.baseline, got, tt.want)
+		}
+	}
+}
+
+func TestCompareSummaries(t *testing.T) {
+	baseline := &v1a1.ApacheBenchSummary{
+		Percentiles:       &v1a1.ApacheBenchPercentiles{P50: 10, P90: 20, P95: 30, P99: 40, P100: 100},
+		RequestsPerSecond: 200,
+		TimePerRequest:    50,
+	}
+
+	tests := []struct {
+		name            string
+		summary         v1a1.ApacheBenchSummary
+		baseline        *v1a1.ApacheBenchSummary
+		maxRegression   float64
+		wantRPSDelta    float64
+		wantMeanDelta   float64
+		wantRegressions []string
+	}{
+		{
+			name: "unchanged",
+			summary: v1a1.ApacheBenchSummary{
+				Percentiles:       &v1a1.ApacheBenchPercentiles{P50: 10, P90: 20, P95: 30, P99: 40, P100: 100},
+				RequestsPerSecond: 200,
+				TimePerRequest:    50,
+			},
+			baseline: baseline,
+		},
+		{
+			name: "improved",
+			summary: v1a1.ApacheBenchSummary{
+				Percentiles:       &v1a1.ApacheBenchPercentiles{P50: 5, P90: 10, P95: 15, P99: 20, P100: 50},
+				RequestsPerSecond: 300,
+				TimePerRequest:    25,
+			},
+			baseline:      baseline,
+			wantRPSDelta:  50,
+			wantMeanDelta: -50,
+		},
+		{
+			name: "regressed",
+			summary: v1a1.ApacheBenchSummary{
+				Percentiles:       &v1a1.ApacheBenchPercentiles{P50: 10, P90: 20, P95: 30, P99: 60, P100: 1000},
+				RequestsPerSecond: 150,
+				TimePerRequest:    60,
+			},
+			baseline:      baseline,
+			maxRegression: 10,
+			wantRPSDelta:  -25,
+			wantMeanDelta: 20,
+			wantRegressions: []string{
+				"requests per second dropped by 25.00% from 200.00 to 150.00",
+				"mean latency rose by 20.00% from 50.000ms to 60.000ms",
+				"p99 latency rose by 50.00% from 40.000ms to 60.000ms",
+			},
+		},
+		{
+			name: "within the allowed regression",
+			summary: v1a1.ApacheBenchSummary{
+				Percentiles:       &v1a1.ApacheBenchPercentiles{P50: 10, P90: 20, P95: 30, P99: 44, P100: 100},
+				RequestsPerSecond: 190,
+				TimePerRequest:    55,
+			},
+			baseline:      baseline,
+			maxRegression: 10,
+			wantRPSDelta:  -5,
+			wantMeanDelta: 10,
+		},
+		{
+			name:          "without percentiles",
+			summary:       v1a1.ApacheBenchSummary{RequestsPerSecond: 100, TimePerRequest: 100},
+			baseline:      &v1a1.ApacheBenchSummary{RequestsPerSecond: 200, TimePerRequest: 50},
+			wantRPSDelta:  -50,
+			wantMeanDelta: 100,
+			wantRegressions: []string{
+				"requests per second dropped by 50.00% from 200.00 to 100.00",
+				"mean latency rose by 100.00% from 50.000ms to 100.000ms",
+			},
+		},
+		{
+			name:     "zero baseline",
+			summary:  v1a1.ApacheBenchSummary{RequestsPerSecond: 100, TimePerRequest: 100},
+			baseline: &v1a1.ApacheBenchSummary{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compareSummaries(&tt.summary, tt.baseline, tt.maxRegression)
+			if !floatsEqual(got.RequestsPerSecondDelta, tt.wantRPSDelta) || !floatsEqual(got.MeanLatencyDelta, tt.wantMeanDelta) {
+				t.Errorf("compareSummaries() deltas = %v, %v, want %v, %v",
+					got.RequestsPerSecondDelta, got.MeanLatencyDelta, tt.wantRPSDelta, tt.wantMeanDelta)
+			}
+			if !reflect.DeepEqual(got.Regressions, tt.wantRegressions) {
+				t.Errorf("compareSummaries() regressions = %q, want %q", got.Regressions, tt.wantRegressions)
+			}
+			if got.Regressed != (len(tt.wantRegressions) > 0) {
+				t.Errorf("compareSummaries() regressed = %t, want %t", got.Regressed, len(tt.wantRegressions) > 0)
+			}
+			if (got.PercentilesDelta != nil) != (tt.summary.Percentiles != nil) {
+				t.Errorf("compareSummaries() percentiles delta = %+v, want it set with the percentiles", got.PercentilesDelta)
+			}
+		})
+	}
+}
+
+func TestGetBaselineRun(t *testing.T) {
+	cr := &v1a1.ApacheBench{
+		Status: v1a1.ApacheBenchStatus{
+			Runs: []v1a1.ApacheBenchRun{
+				newBaselineRun(1, v1a1.ApacheBenchPhaseComplete, 100),
+				newBaselineRun(2, v1a1.ApacheBenchPhaseFailed, 200),
+				newBaselineRun(3, v1a1.ApacheBenchPhaseComplete, 300),
+				newBaselineRun(4, v1a1.ApacheBenchPhaseComplete, 0),
+				newBaselineRun(5, v1a1.ApacheBenchPhaseComplete, 500),
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		number   int32
+		excluded int32
+		want     int32
+	}{
+		{name: "most recent", want: 5},
+		{name: "most recent other than the excluded run", excluded: 5, want: 3},
+		{name: "by number", number: 1, excluded: 5, want: 1},
+		{name: "by number excluded", number: 5, excluded: 5},
+		{name: "failed", number: 2},
+		{name: "without results", number: 4},
+		{name: "not found", number: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getBaselineRun(cr, tt.number, tt.excluded)
+			if tt.want == 0 {
+				if got != nil {
+					t.Errorf("getBaselineRun() = run %d, want nil", got.Number)
+				}
+			} else if got == nil || got.Number != tt.want {
+				t.Errorf("getBaselineRun() = %+v, want run %d", got, tt.want)
+			}
+		})
+	}
+
+	if got := getBaselineRun(&v1a1.ApacheBench{}, 0, 0); got != nil {
+		t.Errorf("getBaselineRun() = %+v without runs, want nil", got)
+	}
+}
+
+func TestUpdateComparison(t *testing.T) {
+	baseline := &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "baseline", Namespace: "benchmark"},
+		Status: v1a1.ApacheBenchStatus{
+			Runs: []v1a1.ApacheBenchRun{newBaselineRun(1, v1a1.ApacheBenchPhaseComplete, 400)},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		spec            *v1a1.ApacheBenchBaselineSpec
+		current         v1a1.ApacheBenchRun
+		want            string
+		wantBaselineRun int32
+		wantErrors      int
+	}{
+		{
+			name:            "previous run",
+			spec:            &v1a1.ApacheBenchBaselineSpec{},
+			current:         newBaselineRun(3, v1a1.ApacheBenchPhaseComplete, 200),
+			want:            "example",
+			wantBaselineRun: 2,
+		},
+		{
+			name:            "another apachebench",
+			spec:            &v1a1.ApacheBenchBaselineSpec{Name: "baseline"},
+			current:         newBaselineRun(3, v1a1.ApacheBenchPhaseComplete, 200),
+			want:            "baseline",
+			wantBaselineRun: 1,
+		},
+		{
+			name:       "another apachebench not found",
+			spec:       &v1a1.ApacheBenchBaselineSpec{Name: "missing"},
+			current:    newBaselineRun(3, v1a1.ApacheBenchPhaseComplete, 200),
+			wantErrors: 1,
+		},
+		{
+			name:    "no baseline run",
+			spec:    &v1a1.ApacheBenchBaselineSpec{Run: 9},
+			current: newBaselineRun(3, v1a1.ApacheBenchPhaseComplete, 200),
+		},
+		{
+			name:    "current run not complete",
+			spec:    &v1a1.ApacheBenchBaselineSpec{},
+			current: newBaselineRun(3, v1a1.ApacheBenchPhaseRunning, 0),
+		},
+		{
+			name:    "no baseline spec",
+			current: newBaselineRun(3, v1a1.ApacheBenchPhaseComplete, 200),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1a1.ApacheBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"},
+				Spec:       v1a1.ApacheBenchSpec{Baseline: tt.spec},
+				Status: v1a1.ApacheBenchStatus{
+					Comparison: &v1a1.ApacheBenchComparison{Baseline: "stale"},
+					Runs: []v1a1.ApacheBenchRun{
+						newBaselineRun(1, v1a1.ApacheBenchPhaseComplete, 100),
+						newBaselineRun(2, v1a1.ApacheBenchPhaseComplete, 250),
+						tt.current,
+					},
+				},
+			}
+			r := newTestReconciler(t, baseline.DeepCopy(), cr.DeepCopy())
+
+			r.updateComparison(cr)
+			got := cr.Status.Comparison
+			if len(tt.want) == 0 {
+				if got != nil {
+					t.Errorf("updateComparison() comparison = %+v, want nil", got)
+				}
+			} else if got == nil || got.Baseline != tt.want || got.BaselineRun != tt.wantBaselineRun {
+				t.Errorf("updateComparison() comparison = %+v, want run %d of %s", got, tt.wantBaselineRun, tt.want)
+			}
+			if len(cr.Status.Errors) != tt.wantErrors {
+				t.Errorf("updateComparison() errors = %q, want %d", cr.Status.Errors, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestMapBaselineResource(t *testing.T) {
+	newBench := func(name string, baseline *v1a1.ApacheBenchBaselineSpec) *v1a1.ApacheBench {
+		return &v1a1.ApacheBench{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "benchmark"},
+			Spec:       v1a1.ApacheBenchSpec{Baseline: baseline, URL: "http://example.com/"},
+		}
+	}
+	r := newTestReconciler(t,
+		newBench("baseline", &v1a1.ApacheBenchBaselineSpec{Name: "baseline"}),
+		newBench("candidate", &v1a1.ApacheBenchBaselineSpec{Name: "baseline"}),
+		newBench("previous", &v1a1.ApacheBenchBaselineSpec{}),
+		newBench("other", nil),
+	)
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "baseline", want: []string{"candidate"}},
+		{name: "candidate"},
+		{name: "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := newBench(tt.name, nil)
+			got := make([]string, 0)
+			for _, request := range mapBaselineResource(r.client, handler.MapObject{Meta: cr, Object: cr}) {
+				got = append(got, request.Name)
+			}
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("mapBaselineResource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// defaultMaxConcurrency is the highest concurrency level to try for a capacity search when not specified.
+	defaultMaxConcurrency = 1000
+
+	// defaultResolution is the precision of the binary search for a capacity search when not specified.
+	defaultResolution = 1
+
+	// defaultStartConcurrency is the first concurrency level to try for a capacity search when not specified.
+	defaultStartConcurrency = 1
+)
+
+// getCapacitySearch will return the options for the capacity search for the given ApacheBench, with the defaults
+// applied.
+func getCapacitySearch(cr *v1a1.ApacheBench) *v1a1.ApacheBenchCapacitySearchSpec {
+	search := &v1a1.ApacheBenchCapacitySearchSpec{}
+	if cr.Spec.CapacitySearch != nil {
+		search = cr.Spec.CapacitySearch.DeepCopy()
+	}
+
+	if search.Limits == nil {
+		search.Limits = &v1a1.ApacheBenchThresholdsSpec{}
+	}
+	if search.MaxConcurrency <= 0 {
+		search.MaxConcurrency = defaultMaxConcurrency
+	}
+	if search.Resolution <= 0 {
+		search.Resolution = defaultResolution
+	}
+	if search.StartConcurrency <= 0 {
+		search.StartConcurrency = defaultStartConcurrency
+	}
+	if search.StartConcurrency > search.MaxConcurrency {
+		search.StartConcurrency = search.MaxConcurrency
+	}
+
+	return search
+}
+
+// getCurrentIteration will return the most recent iteration of the capacity search for the given run, or nil if the
+// run is not a capacity search.
+func getCurrentIteration(run *v1a1.ApacheBenchRun) *v1a1.ApacheBenchCapacityIteration {
+	if run.Capacity == nil || len(run.Capacity.Iterations) <= 0 {
+		return nil
+	}
+	return &run.Capacity.Iterations[len(run.Capacity.Iterations)-1]
+}
+
+// getIterationJobName will return the name of the Job for the iteration of the given run at the given concurrency.
+// Each concurrency level is only tried once for a run, so the name is unique.
+func getIterationJobName(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, concurrency uint32) string {
+	return fmt.Sprintf("%s-c%d", getRunJobName(cr, run.Number), concurrency)
+}
+
+// getLoad will return the concurrency and number of requests for the given run of the given ApacheBench. For a
+// capacity search, the concurrency for the current iteration is used, and the number of requests is raised to the
+// concurrency when there is no time limit, as ab requires at least one request for each concurrent connection.
+func getLoad(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) (uint32, uint32) {
+	concurrency, requests := cr.Spec.Concurrency, cr.Spec.Requests
+
+	if iteration := getCurrentIteration(run); iteration != nil && isCapacitySearch(cr) {
+		concurrency = iteration.Concurrency
+		if cr.Spec.TimeLimit <= 0 && requests < concurrency {
+			requests = concurrency
+		}
+	}
+
+	return concurrency, requests
+}
+
+// getNextConcurrency will return the concurrency level to try next for the capacity search for the given
+// ApacheBench, based on the given iterations so far. The concurrency is doubled until a limit is crossed, then a
+// binary search is used between the highest sustainable and lowest unsustainable levels. True is returned once the
+// search is complete.
+func getNextConcurrency(cr *v1a1.ApacheBench, iterations []v1a1.ApacheBenchCapacityIteration) (uint32, bool) {
+	search := getCapacitySearch(cr)
+	if len(iterations) <= 0 {
+		return search.StartConcurrency, false
+	}
+
+	// The highest sustainable and lowest unsustainable concurrency levels so far, zero when there are none.
+	var lo, hi uint32
+	for _, iteration := range iterations {
+		if iteration.Sustainable {
+			if iteration.Concurrency > lo {
+				lo = iteration.Concurrency
+			}
+		} else if hi == 0 || iteration.Concurrency < hi {
+			hi = iteration.Concurrency
+		}
+	}
+
+	if hi == 0 {
+		if lo >= search.MaxConcurrency {
+			return 0, true
+		}
+
+		next := lo * 2
+		if next > search.MaxConcurrency {
+			next = search.MaxConcurrency
+		}
+		return next, false
+	}
+
+	if hi <= lo+search.Resolution {
+		return 0, true
+	}
+	return lo + (hi-lo)/2, false
+}
+
+// isCapacitySearch will return true if the given ApacheBench searches for the maximum sustainable concurrency.
+func isCapacitySearch(cr *v1a1.ApacheBench) bool {
+	return cr.Spec.Mode == v1a1.ApacheBenchModeCapacitySearch
+}
+
+// isSearchInProgress will return true if the given run is a capacity search that has completed at least one
+// iteration, ie. it has moved on to a later iteration, as each iteration is added before its Job is created. The
+// readiness checks are only used before the first iteration.
+func isSearchInProgress(run *v1a1.ApacheBenchRun) bool {
+	return run.Capacity != nil && len(run.Capacity.Iterations) > 1
+}
+
+// newIteration will add a new iteration at the given concurrency to the capacity search for the given run, and
+// switch the run to the Job for the iteration.
+func newIteration(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, concurrency uint32) {
+	if run.Capacity == nil {
+		run.Capacity = &v1a1.ApacheBenchCapacityResult{}
+	}
+
+	run.Job = getIterationJobName(cr, run, concurrency)
+	run.Capacity.Iterations = append(run.Capacity.Iterations, v1a1.ApacheBenchCapacityIteration{
+		Concurrency: concurrency,
+		Job:         run.Job,
+		Number:      int32(len(run.Capacity.Iterations) + 1),
+	})
+}
+
+// updateCapacitySearch will record the results of the given completed Job for the current iteration of the capacity
+// search for the given run, and create the Job for the next iteration. The status keeps the results at the highest
+// sustainable concurrency so far. True is returned once the search is complete.
+func (r *ReconcileApacheBench) updateCapacitySearch(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, job *batchv1.Job) (bool, error) {
+	iteration := getCurrentIteration(run)
+	if iteration == nil {
+		return true, r.addJobResultsToStatus(cr, run, job) // Started before the capacity search was enabled
+	}
+
+	best := cr.Status.DeepCopy()
+	if err := r.addJobResultsToStatus(cr, run, job); err != nil {
+		return false, err
+	}
+
+	iteration.Breaches = evaluateThresholds(getCapacitySearch(cr).Limits, cr.Status.Aggregate)
+	iteration.CompletionTime = job.Status.CompletionTime
+	iteration.Results = cr.Status.ResultsRefs
+	iteration.Sustainable = len(iteration.Breaches) <= 0
+	if cr.Status.Aggregate != nil {
+		iteration.MeanLatency = cr.Status.Aggregate.TimePerRequest
+		iteration.RequestsPerSecond = cr.Status.Aggregate.RequestsPerSecond
+	}
+
+	log.Info("capacity search iteration complete", "namespace", cr.Namespace, "name", cr.Name, "run", run.Number,
+		"concurrency", iteration.Concurrency, "sustainable", iteration.Sustainable)
+
+	if iteration.Sustainable {
+		iteration.Breaches = nil
+		run.Capacity.Concurrency = iteration.Concurrency
+		run.Capacity.RequestsPerSecond = iteration.RequestsPerSecond
+	} else if run.Capacity.Concurrency > 0 {
+		cr.Status.Aggregate = best.Aggregate
+		cr.Status.ResultsRefs = best.ResultsRefs
+		cr.Status.Stages = best.Stages
+		cr.Status.Summary = best.Summary
+	} else {
+		cr.Status.Aggregate = nil
+		cr.Status.ResultsRefs = nil
+		cr.Status.Stages = nil
+		cr.Status.Summary = nil
+	}
+
+	next, done := getNextConcurrency(cr, run.Capacity.Iterations)
+	if done {
+		return true, nil
+	}
+
+	result := "sustainable"
+	if !iteration.Sustainable {
+		result = "not sustainable"
+	}
+	setCondition(cr, v1a1.ApacheBenchConditionRunning, corev1.ConditionTrue, "CapacitySearch",
+		fmt.Sprintf("concurrency %d is %s, trying concurrency %d", iteration.Concurrency, result, next))
+
+	newIteration(cr, run, next)
+	return false, r.createJob(cr, run)
+}
+
+// validateCapacitySearch will check that the options for the given ApacheBench can be used for a capacity search. If
+// not, the ApacheBench is marked as failed and an error is returned.
+func (r *ReconcileApacheBench) validateCapacitySearch(cr *v1a1.ApacheBench) error {
+	if !isCapacitySearch(cr) || len(cr.Spec.Stages) <= 0 {
+		return nil
+	}
+	return r.failJobCreation(cr, "InvalidMode", "stages cannot be used with the capacitySearch mode")
+}
@@ -0,0 +1,294 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// newCapacitySearch will return an ApacheBench that searches for the maximum concurrency with the given options.
+func newCapacitySearch(search *v1a1.ApacheBenchCapacitySearchSpec) *v1a1.ApacheBench {
+	return &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"},
+		Spec: v1a1.ApacheBenchSpec{
+			CapacitySearch: search,
+			Mode:           v1a1.ApacheBenchModeCapacitySearch,
+			Requests:       100,
+			URL:            "http://example.com/",
+		},
+	}
+}
+
+// iterations will return completed iterations at the given concurrency levels, where a negative level was not
+// sustainable.
+func iterations(levels ...int) []v1a1.ApacheBenchCapacityIteration {
+	its := make([]v1a1.ApacheBenchCapacityIteration, 0)
+	for _, level := range levels {
+		if level < 0 {
+			its = append(its, v1a1.ApacheBenchCapacityIteration{Concurrency: uint32(-level)})
+		} else {
+			its = append(its, v1a1.ApacheBenchCapacityIteration{Concurrency: uint32(level), Sustainable: true})
+		}
+	}
+	return its
+}
+
+func TestGetNextConcurrency(t *testing.T) {
+	tests := []struct {
+		name       string
+		search     *v1a1.ApacheBenchCapacitySearchSpec
+		iterations []v1a1.ApacheBenchCapacityIteration
+		want       uint32
+		wantDone   bool
+	}{
+		{
+			name: "defaults",
+			want: defaultStartConcurrency,
+		},
+		{
+			name:   "start concurrency",
+			search: &v1a1.ApacheBenchCapacitySearchSpec{StartConcurrency: 8},
+			want:   8,
+		},
+		{
+			name:   "start concurrency above the maximum",
+			search: &v1a1.ApacheBenchCapacitySearchSpec{MaxConcurrency: 20, StartConcurrency: 50},
+			want:   20,
+		},
+		{
+			name:       "doubles while sustainable",
+			iterations: iterations(1, 2, 4),
+			want:       8,
+		},
+		{
+			name:       "doubles up to the maximum",
+			search:     &v1a1.ApacheBenchCapacitySearchSpec{MaxConcurrency: 6},
+			iterations: iterations(1, 2, 4),
+			want:       6,
+		},
+		{
+			name:       "sustainable at the maximum",
+			search:     &v1a1.ApacheBenchCapacitySearchSpec{MaxConcurrency: 4},
+			iterations: iterations(1, 2, 4),
+			wantDone:   true,
+		},
+		{
+			name:       "sustainable above the maximum",
+			search:     &v1a1.ApacheBenchCapacitySearchSpec{MaxConcurrency: 3},
+			iterations: iterations(1, 2, 4),
+			wantDone:   true,
+		},
+		{
+			name:       "binary search",
+			iterations: iterations(1, 2, 4, 8, 16, -32),
+			want:       24,
+		},
+		{
+			name:       "binary search below the lowest unsustainable",
+			iterations: iterations(1, 2, 4, 8, 16, -32, 24, -28),
+			want:       26,
+		},
+		{
+			name:       "found",
+			iterations: iterations(1, 2, 4, 8, 16, -32, 24, -28, -26, 25),
+			wantDone:   true,
+		},
+		{
+			name:       "first iteration unsustainable",
+			search:     &v1a1.ApacheBenchCapacitySearchSpec{StartConcurrency: 8},
+			iterations: iterations(-8),
+			want:       4,
+		},
+		{
+			name:       "first iteration unsustainable at the start concurrency of one",
+			iterations: iterations(-1),
+			wantDone:   true,
+		},
+		{
+			name:       "within the resolution",
+			search:     &v1a1.ApacheBenchCapacitySearchSpec{Resolution: 10},
+			iterations: iterations(1, 2, 4, 8, -16),
+			wantDone:   true,
+		},
+		{
+			name:       "outside the resolution",
+			search:     &v1a1.ApacheBenchCapacitySearchSpec{Resolution: 4},
+			iterations: iterations(1, 2, 4, 8, -16),
+			want:       12,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, done := getNextConcurrency(newCapacitySearch(tt.search), tt.iterations)
+			if got != tt.want || done != tt.wantDone {
+				t.Errorf("getNextConcurrency() = %d, %t, want %d, %t", got, done, tt.want, tt.wantDone)
+			}
+		})
+	}
+}
+
+func TestIsSearchInProgress(t *testing.T) {
+	tests := []struct {
+		name string
+		run  v1a1.ApacheBenchRun
+		want bool
+	}{
+		{
+			name: "not a capacity search",
+		},
+		{
+			name: "first iteration",
+			run:  v1a1.ApacheBenchRun{Capacity: &v1a1.ApacheBenchCapacityResult{Iterations: iterations(1)}},
+		},
+		{
+			name: "second iteration",
+			run:  v1a1.ApacheBenchRun{Capacity: &v1a1.ApacheBenchCapacityResult{Iterations: iterations(1, 2)}},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSearchInProgress(&tt.run); got != tt.want {
+				t.Errorf("isSearchInProgress() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestUpdateCapacitySearch runs each search to completion against a stand-in API server, where the mean latency
+// reported by ab is twice the concurrency, and the limit is on the mean latency.
+func TestUpdateCapacitySearch(t *testing.T) {
+	tests := []struct {
+		name            string
+		search          v1a1.ApacheBenchCapacitySearchSpec
+		maxMeanLatency  float64
+		wantIterations  []uint32
+		wantConcurrency uint32
+	}{
+		{
+			name:            "doubles then searches",
+			search:          v1a1.ApacheBenchCapacitySearchSpec{StartConcurrency: 4},
+			maxMeanLatency:  50,
+			wantIterations:  []uint32{4, 8, 16, 32, 24, 28, 26, 25},
+			wantConcurrency: 25,
+		},
+		{
+			name:            "first iteration unsustainable",
+			search:          v1a1.ApacheBenchCapacitySearchSpec{StartConcurrency: 8},
+			maxMeanLatency:  10,
+			wantIterations:  []uint32{8, 4, 6, 5},
+			wantConcurrency: 5,
+		},
+		{
+			name:            "resolution",
+			search:          v1a1.ApacheBenchCapacitySearchSpec{Resolution: 8, StartConcurrency: 4},
+			maxMeanLatency:  50,
+			wantIterations:  []uint32{4, 8, 16, 32, 24},
+			wantConcurrency: 24,
+		},
+		{
+			name:            "start concurrency above the maximum",
+			search:          v1a1.ApacheBenchCapacitySearchSpec{MaxConcurrency: 20, StartConcurrency: 200},
+			maxMeanLatency:  100,
+			wantIterations:  []uint32{20},
+			wantConcurrency: 20,
+		},
+		{
+			name:           "nothing sustainable",
+			maxMeanLatency: 1,
+			wantIterations: []uint32{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search := tt.search
+			search.Limits = &v1a1.ApacheBenchThresholdsSpec{MaxMeanLatency: &tt.maxMeanLatency}
+			cr := newCapacitySearch(&search)
+			r := newTestReconciler(t, cr)
+			logs := make(map[string]string)
+			server := serveTestPodLogs(r, logs)
+			defer server.Close()
+
+			run := &v1a1.ApacheBenchRun{Number: 1}
+			first, _ := getNextConcurrency(cr, nil)
+			newIteration(cr, run, first)
+
+			for done := false; !done; {
+				if len(run.Capacity.Iterations) > 10 {
+					t.Fatalf("the search did not complete: %+v", run.Capacity.Iterations)
+				}
+
+				// The Pod for the current iteration reports a mean latency of twice the concurrency.
+				concurrency := getCurrentIteration(run).Concurrency
+				pod := &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      run.Job + "-pod",
+						Namespace: cr.Namespace,
+						Labels:    map[string]string{"job-name": run.Job},
+					},
+					Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+				}
+				if err := r.client.Create(context.TODO(), pod); err != nil {
+					t.Fatal(err)
+				}
+				logs[pod.Name] = strings.Replace(abHeader, "Time per request:       20.600 [ms] (mean)\n",
+					fmt.Sprintf("Time per request:       %d.000 [ms] (mean)\n", concurrency*2), 1)
+
+				job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: run.Job, Namespace: cr.Namespace}}
+				var err error
+				if done, err = r.updateCapacitySearch(cr, run, job); err != nil {
+					t.Fatalf("updateCapacitySearch() returned an error: %v", err)
+				}
+			}
+
+			got := make([]uint32, 0)
+			for _, iteration := range run.Capacity.Iterations {
+				got = append(got, iteration.Concurrency)
+				if want := iteration.Concurrency*2 <= uint32(tt.maxMeanLatency); iteration.Sustainable != want {
+					t.Errorf("iteration at concurrency %d sustainable = %t, want %t",
+						iteration.Concurrency, iteration.Sustainable, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.wantIterations) {
+				t.Errorf("iterations = %v, want %v", got, tt.wantIterations)
+			}
+			if run.Capacity.Concurrency != tt.wantConcurrency {
+				t.Errorf("capacity concurrency = %d, want %d", run.Capacity.Concurrency, tt.wantConcurrency)
+			}
+
+			// The status keeps the results at the highest sustainable concurrency.
+			if tt.wantConcurrency <= 0 {
+				if cr.Status.Aggregate != nil {
+					t.Errorf("expected no results, got %+v", cr.Status.Aggregate)
+				}
+			} else if cr.Status.Aggregate == nil || cr.Status.Aggregate.TimePerRequest != float64(tt.wantConcurrency*2) {
+				t.Errorf("expected the results at concurrency %d, got %+v", tt.wantConcurrency, cr.Status.Aggregate)
+			}
+		})
+	}
+}
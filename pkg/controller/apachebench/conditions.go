@@ -0,0 +1,84 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// getCondition will return the condition with the given type from the status of the given ApacheBench, or nil if the
+// condition is not present.
+func getCondition(cr *v1a1.ApacheBench, condType string) *v1a1.ApacheBenchCondition {
+	for i := range cr.Status.Conditions {
+		if cr.Status.Conditions[i].Type == condType {
+			return &cr.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// getJobCondition will return the condition with the given type from the status of the given Job, or nil if the
+// condition is not present or is not true.
+func getJobCondition(job *batchv1.Job, condType batchv1.JobConditionType) *batchv1.JobCondition {
+	for i := range job.Status.Conditions {
+		cond := &job.Status.Conditions[i]
+		if cond.Type == condType && cond.Status == corev1.ConditionTrue {
+			return cond
+		}
+	}
+	return nil
+}
+
+// removeCondition will remove the condition with the given type from the status of the given ApacheBench.
+func removeCondition(cr *v1a1.ApacheBench, condType string) {
+	for i, cond := range cr.Status.Conditions {
+		if cond.Type == condType {
+			cr.Status.Conditions = append(cr.Status.Conditions[:i], cr.Status.Conditions[i+1:]...)
+			return
+		}
+	}
+}
+
+// resetConditions will reset the conditions on the given ApacheBench for the start of a new run.
+func resetConditions(cr *v1a1.ApacheBench, reason string, message string) {
+	setCondition(cr, v1a1.ApacheBenchConditionJobCreated, corev1.ConditionFalse, reason, message)
+	setCondition(cr, v1a1.ApacheBenchConditionRunning, corev1.ConditionFalse, reason, message)
+	setCondition(cr, v1a1.ApacheBenchConditionSucceeded, corev1.ConditionFalse, reason, message)
+	setCondition(cr, v1a1.ApacheBenchConditionFailed, corev1.ConditionFalse, reason, message)
+	setCondition(cr, v1a1.ApacheBenchConditionResultsCollected, corev1.ConditionFalse, reason, message)
+}
+
+// setCondition will set the condition with the given type on the status of the given ApacheBench.
+// The LastTransitionTime is only updated when the status of the condition changes.
+func setCondition(cr *v1a1.ApacheBench, condType string, status corev1.ConditionStatus, reason string, message string) {
+	cond := getCondition(cr, condType)
+	if cond == nil {
+		cr.Status.Conditions = append(cr.Status.Conditions, v1a1.ApacheBenchCondition{Type: condType})
+		cond = &cr.Status.Conditions[len(cr.Status.Conditions)-1]
+	}
+
+	if cond.Status != status {
+		cond.LastTransitionTime = metav1.Now()
+	}
+
+	cond.Message = message
+	cond.ObservedGeneration = cr.Generation
+	cond.Reason = reason
+	cond.Status = status
+}
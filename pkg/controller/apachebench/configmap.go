@@ -0,0 +1,164 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// maxResultsChunkSize is the maximum size in bytes of the output stored in a single ConfigMap. This leaves room
+	// for the rest of the object within the size limit for objects stored in etcd.
+	maxResultsChunkSize = 900 * 1024
+
+	// resultsKey is the key in each results ConfigMap that contains the stored data.
+	resultsKey = "output"
+
+	// runLabel is the label on each results ConfigMap that contains the number of the run.
+	runLabel = "httpd.apache.org/run"
+)
+
+// getStoredResults will return the complete data that is stored in the ConfigMaps with the given names.
+func (r *ReconcileApacheBench) getStoredResults(namespace string, names []string) ([]byte, error) {
+	output := new(bytes.Buffer)
+
+	for _, name := range names {
+		cm := &corev1.ConfigMap{}
+		if err := r.fetchObject(namespace, name, cm); err != nil {
+			return nil, err
+		}
+
+		if data, ok := cm.BinaryData[resultsKey]; ok {
+			output.Write(data)
+		} else {
+			output.WriteString(cm.Data[resultsKey])
+		}
+	}
+
+	return output.Bytes(), nil
+}
+
+// newResultsConfigMap returns a new ConfigMap instance for the given chunk of the given kind of results from the given
+// Pod.
+func newResultsConfigMap(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, pod corev1.Pod, kind string, index int) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s-%d", pod.Name, kind, index),
+			Namespace: cr.Namespace,
+			Labels: map[string]string{
+				apacheBenchLabel: cr.Name,
+				runLabel:         strconv.Itoa(int(run.Number)),
+			},
+		},
+	}
+}
+
+// splitResults will split the given output into chunks no larger than the given size.
+// Each chunk ends on a line boundary where possible.
+func splitResults(output []byte, size int) [][]byte {
+	chunks := make([][]byte, 0)
+
+	for len(output) > size {
+		end := bytes.LastIndexByte(output[:size], '\n') + 1
+		if end <= 0 {
+			end = size // No line boundary, split the line
+		}
+		chunks = append(chunks, output[:end])
+		output = output[end:]
+	}
+
+	return append(chunks, output)
+}
+
+// storeResults will store the given output and sections from the given Pod in ConfigMaps, and return a reference to
+// the stored results. The ConfigMaps are owned by the given Job, so that they are removed along with the run.
+func (r *ReconcileApacheBench) storeResults(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, job *batchv1.Job, pod corev1.Pod, output string, sections map[string]string) (*v1a1.ApacheBenchResultsReference, error) {
+	ref := &v1a1.ApacheBenchResultsReference{
+		Pod:  pod.Name,
+		Size: int64(len(output)),
+	}
+
+	var err error
+	if ref.ConfigMaps, err = r.storeResultsFile(cr, run, job, pod, "results", []byte(output)); err != nil {
+		return nil, err
+	}
+
+	if data, ok := sections[csvSection]; ok {
+		if ref.CSVConfigMaps, err = r.storeResultsFile(cr, run, job, pod, csvSection, []byte(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if data, ok := sections[gnuplotSection]; ok {
+		if ref.GnuplotConfigMaps, err = r.storeResultsFile(cr, run, job, pod, gnuplotSection, []byte(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	return ref, nil
+}
+
+// storeResultsFile will store the given kind of results from the given Pod in one or more ConfigMaps, and return the
+// names of the ConfigMaps in order.
+func (r *ReconcileApacheBench) storeResultsFile(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, job *batchv1.Job, pod corev1.Pod, kind string, data []byte) ([]string, error) {
+	names := make([]string, 0)
+
+	for i, chunk := range splitResults(data, maxResultsChunkSize) {
+		cm := newResultsConfigMap(cr, run, pod, kind, i)
+
+		// The output may contain response bodies when the verbosity is high, which are not always valid UTF-8.
+		if utf8.Valid(chunk) {
+			cm.Data = map[string]string{resultsKey: string(chunk)}
+		} else {
+			cm.BinaryData = map[string][]byte{resultsKey: chunk}
+		}
+
+		if err := controllerutil.SetControllerReference(job, cm, r.scheme); err != nil {
+			return nil, err
+		}
+
+		if err := r.client.Create(context.TODO(), cm); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return nil, err
+			}
+
+			existing := &corev1.ConfigMap{}
+			if err := r.fetchObject(cm.Namespace, cm.Name, existing); err != nil {
+				return nil, err
+			}
+			existing.Data = cm.Data
+			existing.BinaryData = cm.BinaryData
+			if err := r.client.Update(context.TODO(), existing); err != nil {
+				return nil, err
+			}
+		}
+
+		names = append(names, cm.Name)
+	}
+
+	return names, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSplitResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		size   int
+		want   []string
+	}{
+		{
+			name: "empty",
+			size: 4,
+			want: []string{""},
+		},
+		{
+			name:   "within the size",
+			output: "ab\ncd",
+			size:   8,
+			want:   []string{"ab\ncd"},
+		},
+		{
+			name:   "exact size",
+			output: "abc\n",
+			size:   4,
+			want:   []string{"abc\n"},
+		},
+		{
+			name:   "exact multiple of the size",
+			output: "abc\ndef\nghi\n",
+			size:   4,
+			want:   []string{"abc\n", "def\n", "ghi\n"},
+		},
+		{
+			name:   "on the last line boundary",
+			output: "a\nb\ncdef\ng",
+			size:   5,
+			want:   []string{"a\nb\n", "cdef\n", "g"},
+		},
+		{
+			name:   "no line boundary within the size",
+			output: "abcdefghij\nklmno",
+			size:   4,
+			want:   []string{"abcd", "efgh", "ij\n", "klmn", "o"},
+		},
+		{
+			name:   "no line boundary",
+			output: "abcdefgh",
+			size:   4,
+			want:   []string{"abcd", "efgh"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := splitResults([]byte(tt.output), tt.size)
+			got := make([]string, 0)
+			for _, chunk := range chunks {
+				if len(chunk) > tt.size {
+					t.Errorf("chunk %q is larger than %d bytes", chunk, tt.size)
+				}
+				got = append(got, string(chunk))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitResults() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreResultsFile(t *testing.T) {
+	// A line longer than the chunk size is split within the multi-byte rune that spans the boundary.
+	long := strings.Repeat("a", maxResultsChunkSize-1) + "é" + strings.Repeat("b", 10)
+
+	tests := []struct {
+		name       string
+		data       []byte
+		wantBinary []bool
+	}{
+		{
+			name:       "text",
+			data:       []byte("Requests per second:    485.44 [#/sec] (mean)\n"),
+			wantBinary: []bool{false},
+		},
+		{
+			name:       "empty",
+			wantBinary: []bool{false},
+		},
+		{
+			name:       "not utf-8",
+			data:       []byte("HTTP/1.1 200 OK\n\xff\xfe\x00body\n"),
+			wantBinary: []bool{true},
+		},
+		{
+			name:       "rune split across chunks",
+			data:       []byte(long),
+			wantBinary: []bool{true, true},
+		},
+		{
+			name:       "chunks",
+			data:       []byte(strings.Repeat(strings.Repeat("x", 1023)+"\n", 2*maxResultsChunkSize/1024+1)),
+			wantBinary: []bool{false, false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1a1.ApacheBench{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"}}
+			run := &v1a1.ApacheBenchRun{Job: "example-1", Number: 1}
+			job := newJob(cr, run.Job)
+			job.UID = "job-uid"
+			pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "example-1-abcde", Namespace: cr.Namespace}}
+			r := newTestReconciler(t, cr)
+
+			// Storing the results again updates the existing ConfigMaps.
+			for i := 0; i < 2; i++ {
+				names, err := r.storeResultsFile(cr, run, job, pod, "results", tt.data)
+				if err != nil {
+					t.Fatalf("storeResultsFile() returned an error: %v", err)
+				}
+				if len(names) != len(tt.wantBinary) {
+					t.Fatalf("storeResultsFile() = %v, want %d ConfigMap(s)", names, len(tt.wantBinary))
+				}
+
+				for j, name := range names {
+					cm := &corev1.ConfigMap{}
+					if err := r.fetchObject(cr.Namespace, name, cm); err != nil {
+						t.Fatal(err)
+					}
+					_, binary := cm.BinaryData[resultsKey]
+					_, text := cm.Data[resultsKey]
+					if binary != tt.wantBinary[j] || text == tt.wantBinary[j] {
+						t.Errorf("ConfigMap %s has binary data = %t, text data = %t, want binary data = %t",
+							name, binary, text, tt.wantBinary[j])
+					}
+					if cm.Labels[apacheBenchLabel] != cr.Name || cm.Labels[runLabel] != "1" {
+						t.Errorf("ConfigMap %s labels = %v, want the name and run", name, cm.Labels)
+					}
+					if owner := metav1.GetControllerOf(cm); owner == nil || owner.Kind != "Job" || owner.Name != job.Name {
+						t.Errorf("ConfigMap %s owner = %+v, want Job %s", name, owner, job.Name)
+					}
+				}
+
+				got, err := r.getStoredResults(cr.Namespace, names)
+				if err != nil {
+					t.Fatalf("getStoredResults() returned an error: %v", err)
+				}
+				if !bytes.Equal(got, tt.data) {
+					t.Errorf("getStoredResults() returned %d bytes, want the %d bytes stored", len(got), len(tt.data))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+const (
+	// endpointSectionPrefix is the prefix of the name of each section of the Pod logs that contains the output for an
+	// endpoint. The prefix is followed by the number of the endpoint, starting at one.
+	endpointSectionPrefix = "endpoint-"
+)
+
+// combineEndpointSummaries will combine the given summaries for each endpoint from a single Pod into the total for the
+// Pod. When the endpoints are run in turn, the time taken is the sum for every endpoint and the throughput is based on
+// the total time, rather than the sum of the throughput for each endpoint.
+func combineEndpointSummaries(cr *v1a1.ApacheBench, summaries []v1a1.ApacheBenchSummary) *v1a1.ApacheBenchSummary {
+	total := aggregateSummaries(summaries)
+	if total == nil {
+		return nil
+	}
+
+	// The document is different for each endpoint.
+	total.DocumentLength = 0
+	total.DocumentPath = ""
+
+	if isParallelEndpoints(cr) {
+		return total
+	}
+
+	total.ConcurrencyLevel = 0
+	total.TimeTaken = 0
+	for _, s := range summaries {
+		if s.ConcurrencyLevel > total.ConcurrencyLevel {
+			total.ConcurrencyLevel = s.ConcurrencyLevel
+		}
+		total.TimeTaken += s.TimeTaken
+	}
+
+	total.RequestsPerSecond, total.TimePerRequestAcrossConcurrency, total.TransferRate = 0, 0, 0
+	if total.TimeTaken > 0 {
+		total.RequestsPerSecond = float64(total.CompleteRequests) / total.TimeTaken
+		total.TransferRate = float64(total.TotalTransferred) / 1024 / total.TimeTaken
+	}
+	if total.RequestsPerSecond > 0 {
+		total.TimePerRequestAcrossConcurrency = 1000 / total.RequestsPerSecond
+	}
+
+	return total
+}
+
+// getEndpointArgs will return the ab options and URL for the endpoint of the given ApacheBench with the given index,
+// quoted for the script. The given URL of the run is used as the base for an endpoint URL that is a path.
+func getEndpointArgs(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, index int) string {
+	endpoint := cr.Spec.Endpoints[index]
+	concurrency, requests, timeLimit := getEndpointLoad(cr, index)
+
+	args := make([]string, 0)
+	if concurrency > 1 {
+		args = append(args, "-c", strconv.FormatUint(uint64(concurrency), 10))
+	}
+
+	// The time limit comes first, as ab sets the number of requests to 50000 for -t.
+	if timeLimit > 0 {
+		args = append(args, "-t", strconv.FormatUint(uint64(timeLimit), 10))
+	}
+
+	if requests > 1 {
+		args = append(args, "-n", strconv.FormatUint(uint64(requests), 10))
+	}
+
+	contentType := endpoint.ContentType
+	if len(contentType) <= 0 {
+		contentType = cr.Spec.ContentType
+	}
+	if len(contentType) > 0 {
+		args = append(args, "-T", contentType)
+	}
+
+	for _, header := range getEndpointHeaders(cr, endpoint) {
+		args = append(args, "-H", header)
+	}
+
+	method := endpoint.HTTPMethod
+	if len(method) <= 0 {
+		method = cr.Spec.HTTPMethod
+	}
+	if len(method) > 0 {
+		args = append(args, "-m", method)
+	}
+
+	if len(endpoint.POSTDataKey) > 0 {
+		args = append(args, "-p", fmt.Sprintf("/data/%s", endpoint.POSTDataKey))
+	}
+
+	if len(endpoint.PUTDataKey) > 0 {
+		args = append(args, "-u", fmt.Sprintf("/data/%s", endpoint.PUTDataKey))
+	}
+
+	// The URL is passed after the common options from the positional parameters, so that it remains last.
+	for i, arg := range args {
+		args[i] = shellQuote(arg)
+	}
+	return strings.Join(append(args, `"$@"`, shellQuote(getEndpointURL(endpoint, run.URL))), " ")
+}
+
+// getEndpointHeaders will return the headers for the requests to the given endpoint of the given ApacheBench, sorted
+// by name. The headers for the endpoint replace the headers for the benchmark with the same name.
+func getEndpointHeaders(cr *v1a1.ApacheBench, endpoint v1a1.ApacheBenchEndpointSpec) []string {
+	headers := make(map[string]string)
+	for key, val := range cr.Spec.Headers {
+		headers[strings.ToLower(key)] = fmt.Sprintf("%s: %s", key, val)
+	}
+	for key, val := range endpoint.Headers {
+		headers[strings.ToLower(key)] = fmt.Sprintf("%s: %s", key, val)
+	}
+
+	keys := make([]string, 0)
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0)
+	for _, key := range keys {
+		values = append(values, headers[key])
+	}
+	return values
+}
+
+// getEndpointLoad will return the concurrency, number of requests and time limit for the endpoint of the given
+// ApacheBench with the given index. The requests are shared between the endpoints by weight, along with the
+// concurrency when the endpoints are run in parallel, or the time limit when they are run in turn.
+func getEndpointLoad(cr *v1a1.ApacheBench, index int) (uint32, uint32, uint32) {
+	total := uint32(0)
+	for _, endpoint := range cr.Spec.Endpoints {
+		total += getEndpointWeight(endpoint)
+	}
+	weight := getEndpointWeight(cr.Spec.Endpoints[index])
+
+	concurrency, requests, timeLimit := cr.Spec.Concurrency, cr.Spec.Requests, cr.Spec.TimeLimit
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	if isParallelEndpoints(cr) {
+		concurrency = getWeightedShare(concurrency, weight, total)
+	} else if timeLimit > 0 {
+		timeLimit = getWeightedShare(timeLimit, weight, total)
+	}
+
+	if requests > 0 {
+		requests = getWeightedShare(requests, weight, total)
+	}
+
+	// ab requires at least one request for each concurrent connection, and performs a single request by default.
+	if timeLimit <= 0 && concurrency > requests {
+		concurrency = requests
+		if concurrency <= 0 {
+			concurrency = 1
+		}
+	}
+
+	return concurrency, requests, timeLimit
+}
+
+// getEndpointOutputs will return the output for each endpoint of the given ApacheBench from the given sections of the
+// Pod logs, in order. Nil is returned if there are no endpoints.
+func getEndpointOutputs(cr *v1a1.ApacheBench, sections map[string]string) []string {
+	if len(cr.Spec.Endpoints) <= 0 {
+		return nil
+	}
+
+	outputs := make([]string, len(cr.Spec.Endpoints))
+	for i := range cr.Spec.Endpoints {
+		outputs[i] = sections[getEndpointSection(i)]
+	}
+	return outputs
+}
+
+// getEndpointSection will return the name of the section of the Pod logs that contains the output for the endpoint
+// with the given index.
+func getEndpointSection(index int) string {
+	return endpointSectionPrefix + strconv.Itoa(index+1)
+}
+
+// getEndpointURL will return the URL to benchmark for the given endpoint. A path is appended to the given base URL.
+func getEndpointURL(endpoint v1a1.ApacheBenchEndpointSpec, base string) string {
+	if !strings.HasPrefix(endpoint.URL, "/") {
+		return endpoint.URL
+	}
+	return strings.TrimSuffix(base, "/") + endpoint.URL
+}
+
+// getEndpointWeight will return the weight of the given endpoint.
+func getEndpointWeight(endpoint v1a1.ApacheBenchEndpointSpec) uint32 {
+	if endpoint.Weight <= 0 {
+		return 1
+	}
+	return endpoint.Weight
+}
+
+// getWeightedShare will return the share of the given value for the given weight out of the given total weight.
+// The share is at least one.
+func getWeightedShare(value uint32, weight uint32, total uint32) uint32 {
+	share := uint32(uint64(value) * uint64(weight) / uint64(total))
+	if share <= 0 {
+		return 1
+	}
+	return share
+}
+
+// isParallelEndpoints will return true if the endpoints of the given ApacheBench are run at the same time.
+func isParallelEndpoints(cr *v1a1.ApacheBench) bool {
+	return cr.Spec.EndpointMode == v1a1.ApacheBenchEndpointModeParallel
+}
+
+// joinEndpointOutputs will return the given output for each endpoint combined, with a line that marks the start of
+// each endpoint, so that the output can be stored as a whole.
+func joinEndpointOutputs(outputs []string) string {
+	b := &strings.Builder{}
+	for i, output := range outputs {
+		fmt.Fprintf(b, "%s%s%s\n", outputSectionPrefix, getEndpointSection(i), outputSectionSuffix)
+		b.WriteString(output)
+	}
+	return b.String()
+}
+
+// newEndpointResults will return the combined results for each endpoint of the given run of the given ApacheBench,
+// using the given parsed results from each Pod for each endpoint. Nil is returned if there are no endpoints.
+func newEndpointResults(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, summaries [][]v1a1.ApacheBenchSummary) []v1a1.ApacheBenchEndpointResult {
+	if len(cr.Spec.Endpoints) <= 0 {
+		return nil
+	}
+
+	results := make([]v1a1.ApacheBenchEndpointResult, 0)
+	for i, endpoint := range cr.Spec.Endpoints {
+		concurrency, requests, timeLimit := getEndpointLoad(cr, i)
+		result := v1a1.ApacheBenchEndpointResult{
+			Concurrency: concurrency,
+			Name:        endpoint.Name,
+			Requests:    requests,
+			TimeLimit:   timeLimit,
+			URL:         getEndpointURL(endpoint, run.URL),
+		}
+		if i < len(summaries) {
+			result.Aggregate = aggregateSummaries(summaries[i])
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// shellQuote will return the given value quoted for a shell script, so that it is not interpreted by the shell.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// validateEndpoints will check that each endpoint of the given ApacheBench has a unique name and a URL, and that a
+// base URL is available for the given run when an endpoint URL is a path. If any are invalid, the ApacheBench is
+// marked as failed and an error is returned.
+func (r *ReconcileApacheBench) validateEndpoints(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) error {
+	if len(cr.Spec.Endpoints) <= 0 {
+		return nil
+	}
+
+	if len(cr.Spec.Stages) > 0 || isCapacitySearch(cr) {
+		return r.failJobCreation(cr, "InvalidEndpoints",
+			"endpoints cannot be used with stages or the capacitySearch mode")
+	}
+
+	var failed = false
+	names := make(map[string]bool)
+
+	for _, endpoint := range cr.Spec.Endpoints {
+		var msg string
+		switch {
+		case len(endpoint.Name) <= 0:
+			msg = "a name must be set for each endpoint"
+		case names[endpoint.Name]:
+			msg = fmt.Sprintf("the endpoint name '%s' is used more than once", endpoint.Name)
+		case len(endpoint.URL) <= 0:
+			msg = fmt.Sprintf("a url must be set for endpoint '%s'", endpoint.Name)
+		case strings.HasPrefix(endpoint.URL, "/") && len(run.URL) <= 0:
+			msg = fmt.Sprintf("a url or target must be set for the path of endpoint '%s'", endpoint.Name)
+		default:
+			names[endpoint.Name] = true
+			continue
+		}
+
+		failed = true
+		names[endpoint.Name] = true
+		cr.Status.AddError(msg)
+	}
+
+	if failed {
+		return r.failJobCreation(cr, "InvalidEndpoints", "invalid endpoint")
+	}
+
+	return nil
+}
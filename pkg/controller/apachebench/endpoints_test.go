@@ -0,0 +1,263 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"os/exec"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+// endpointLoad is the concurrency, number of requests and time limit for an endpoint.
+type endpointLoad struct {
+	concurrency uint32
+	requests    uint32
+	timeLimit   uint32
+}
+
+func TestGetEndpointArgs(t *testing.T) {
+	cr := &v1a1.ApacheBench{
+		Spec: v1a1.ApacheBenchSpec{
+			Concurrency: 10,
+			ContentType: "application/json",
+			Endpoints: []v1a1.ApacheBenchEndpointSpec{
+				{Headers: map[string]string{"Accept": "text/html"}, Name: "home", URL: "/"},
+				{HTTPMethod: "POST", Name: "search", POSTDataKey: "query.json", URL: "http://search.example.com/q", Weight: 3},
+			},
+			Headers:   map[string]string{"Accept": "*/*", "X-Test": "it's"},
+			Requests:  400,
+			TimeLimit: 60,
+		},
+	}
+	run := &v1a1.ApacheBenchRun{URL: "http://example.com/app/"}
+
+	want := []string{
+		`'-c' '10' '-t' '15' '-n' '100' '-T' 'application/json' '-H' 'Accept: text/html' '-H' 'X-Test: it'\''s' "$@" ` +
+			`'http://example.com/app/'`,
+		`'-c' '10' '-t' '45' '-n' '300' '-T' 'application/json' '-H' 'Accept: */*' '-H' 'X-Test: it'\''s' '-m' 'POST' ` +
+			`'-p' '/data/query.json' "$@" 'http://search.example.com/q'`,
+	}
+	for i := range want {
+		if got := getEndpointArgs(cr, run, i); got != want[i] {
+			t.Errorf("getEndpointArgs(%d) = %s, want %s", i, got, want[i])
+		}
+	}
+}
+
+func TestGetEndpointLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    v1a1.ApacheBenchSpec
+		weights []uint32
+		want    []endpointLoad
+	}{
+		{
+			name:    "in turn",
+			spec:    v1a1.ApacheBenchSpec{Concurrency: 10, Requests: 400},
+			weights: []uint32{1, 3},
+			want:    []endpointLoad{{10, 100, 0}, {10, 300, 0}},
+		},
+		{
+			name:    "in turn with a time limit",
+			spec:    v1a1.ApacheBenchSpec{Concurrency: 10, TimeLimit: 60},
+			weights: []uint32{1, 2},
+			want:    []endpointLoad{{10, 0, 20}, {10, 0, 40}},
+		},
+		{
+			name:    "in parallel",
+			spec:    v1a1.ApacheBenchSpec{Concurrency: 8, EndpointMode: v1a1.ApacheBenchEndpointModeParallel, Requests: 400},
+			weights: []uint32{1, 3},
+			want:    []endpointLoad{{2, 100, 0}, {6, 300, 0}},
+		},
+		{
+			name:    "in parallel with a time limit",
+			spec:    v1a1.ApacheBenchSpec{Concurrency: 9, EndpointMode: v1a1.ApacheBenchEndpointModeParallel, TimeLimit: 60},
+			weights: []uint32{1, 2},
+			want:    []endpointLoad{{3, 0, 60}, {6, 0, 60}},
+		},
+		{
+			name:    "in parallel with a share of at least one",
+			spec:    v1a1.ApacheBenchSpec{Concurrency: 2, EndpointMode: v1a1.ApacheBenchEndpointModeParallel, Requests: 30},
+			weights: []uint32{0, 0, 0},
+			want:    []endpointLoad{{1, 10, 0}, {1, 10, 0}, {1, 10, 0}},
+		},
+		{
+			name:    "concurrency limited to the requests",
+			spec:    v1a1.ApacheBenchSpec{Concurrency: 5, Requests: 10},
+			weights: []uint32{1, 9},
+			want:    []endpointLoad{{1, 1, 0}, {5, 9, 0}},
+		},
+		{
+			name:    "defaults",
+			weights: []uint32{0, 0},
+			want:    []endpointLoad{{1, 0, 0}, {1, 0, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1a1.ApacheBench{Spec: tt.spec}
+			for _, weight := range tt.weights {
+				cr.Spec.Endpoints = append(cr.Spec.Endpoints, v1a1.ApacheBenchEndpointSpec{Weight: weight})
+			}
+
+			for i, want := range tt.want {
+				concurrency, requests, timeLimit := getEndpointLoad(cr, i)
+				if got := (endpointLoad{concurrency, requests, timeLimit}); got != want {
+					t.Errorf("getEndpointLoad(%d) = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCombineEndpointSummaries(t *testing.T) {
+	summaries := []v1a1.ApacheBenchSummary{
+		{
+			CompleteRequests:  100,
+			ConcurrencyLevel:  5,
+			DocumentLength:    10,
+			DocumentPath:      "/a",
+			RequestsPerSecond: 50,
+			TimePerRequest:    100,
+			TimeTaken:         2,
+			TotalTransferred:  102400,
+			TransferRate:      50,
+		},
+		{
+			CompleteRequests:  300,
+			ConcurrencyLevel:  10,
+			DocumentLength:    20,
+			DocumentPath:      "/b",
+			RequestsPerSecond: 300,
+			TimePerRequest:    20,
+			TimeTaken:         1,
+			TotalTransferred:  204800,
+			TransferRate:      200,
+		},
+	}
+
+	tests := []struct {
+		name                     string
+		mode                     string
+		summaries                []v1a1.ApacheBenchSummary
+		wantConcurrency          int32
+		wantRequestsPerSecond    float64
+		wantTimePerRequest       float64
+		wantTimePerRequestAcross float64
+		wantTimeTaken            float64
+		wantTransferRate         float64
+	}{
+		{
+			name:                     "in turn",
+			summaries:                summaries,
+			wantConcurrency:          10,
+			wantRequestsPerSecond:    400.0 / 3,
+			wantTimePerRequest:       40,
+			wantTimePerRequestAcross: 7.5,
+			wantTimeTaken:            3,
+			wantTransferRate:         100,
+		},
+		{
+			name:                     "in parallel",
+			mode:                     v1a1.ApacheBenchEndpointModeParallel,
+			summaries:                summaries,
+			wantConcurrency:          15,
+			wantRequestsPerSecond:    350,
+			wantTimePerRequest:       40,
+			wantTimePerRequestAcross: 1000.0 / 350,
+			wantTimeTaken:            2,
+			wantTransferRate:         250,
+		},
+		{
+			name:            "in turn without a time taken",
+			summaries:       []v1a1.ApacheBenchSummary{{CompleteRequests: 1, ConcurrencyLevel: 1, RequestsPerSecond: 10}},
+			wantConcurrency: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1a1.ApacheBench{Spec: v1a1.ApacheBenchSpec{EndpointMode: tt.mode}}
+			got := combineEndpointSummaries(cr, tt.summaries)
+			if got == nil {
+				t.Fatalf("combineEndpointSummaries() = nil")
+			}
+
+			if got.DocumentLength != 0 || len(got.DocumentPath) > 0 {
+				t.Errorf("document = %q (%d bytes), want none", got.DocumentPath, got.DocumentLength)
+			}
+			if got.ConcurrencyLevel != tt.wantConcurrency {
+				t.Errorf("concurrency = %d, want %d", got.ConcurrencyLevel, tt.wantConcurrency)
+			}
+
+			checks := []struct {
+				field string
+				got   float64
+				want  float64
+			}{
+				{"requests per second", got.RequestsPerSecond, tt.wantRequestsPerSecond},
+				{"time per request", got.TimePerRequest, tt.wantTimePerRequest},
+				{"time per request across concurrency", got.TimePerRequestAcrossConcurrency, tt.wantTimePerRequestAcross},
+				{"time taken", got.TimeTaken, tt.wantTimeTaken},
+				{"transfer rate", got.TransferRate, tt.wantTransferRate},
+			}
+			for _, c := range checks {
+				if !floatsEqual(c.got, c.want) {
+					t.Errorf("%s = %v, want %v", c.field, c.got, c.want)
+				}
+			}
+		})
+	}
+
+	if got := combineEndpointSummaries(&v1a1.ApacheBench{}, nil); got != nil {
+		t.Errorf("combineEndpointSummaries() = %+v for no summaries, want nil", got)
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"", "''"},
+		{"-c", "'-c'"},
+		{"Content-Type: text/plain", "'Content-Type: text/plain'"},
+		{"it's", `'it'\''s'`},
+		{"''", `''\'''\'''`},
+		{"$HOME `id` \"x\" \\n", "'$HOME `id` \"x\" \\n'"},
+	}
+
+	sh, err := exec.LookPath("sh")
+	for _, tt := range tests {
+		got := shellQuote(tt.value)
+		if got != tt.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+
+		// The shell reads the quoted value back unchanged.
+		if err != nil {
+			continue
+		}
+		out, err := exec.Command(sh, "-c", "printf '%s' "+got).Output()
+		if err != nil {
+			t.Fatalf("sh returned an error for %s: %v", got, err)
+		}
+		if string(out) != tt.value {
+			t.Errorf("sh read %s as %q, want %q", got, out, tt.value)
+		}
+	}
+}
@@ -17,71 +17,248 @@ package apachebench
 import (
 	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io"
+	"path"
+	"reflect"
 	"strconv"
+	"strings"
+	"time"
 
 	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
+	// apacheBenchLabel is the label on each benchmark Pod that contains the name of the ApacheBench.
+	apacheBenchLabel = "httpd.apache.org/apachebench"
+
+	// benchmarkContainerName is the name of the container in each benchmark Pod that runs the benchmark.
+	benchmarkContainerName = "benchmark"
+
+	// clientCertificateFile is the name of the file in the TLS volume that contains the client certificate.
+	clientCertificateFile = "client.pem"
+
 	// defaultContainerImage is the container image to use when one is not specified in the CR.
 	defaultContainerImage = "httpd@sha256:223b88ef9a99261b07d2025d43799f45cace9b7b208195078b42cc2b922e453c" // 2.4.43-alpine
+
+	// proxyPasswordKey is the key in the Secret that contains the password for the proxy.
+	proxyPasswordKey = "proxy.password"
+
+	// proxyUsernameKey is the key in the Secret that contains the username for the proxy.
+	proxyUsernameKey = "proxy.username"
+
+	// requestPasswordKey is the key in the Secret that contains the password for the request.
+	requestPasswordKey = "request.password"
+
+	// requestUsernameKey is the key in the Secret that contains the username for the request.
+	requestUsernameKey = "request.username"
+
+	// tlsMountPath is the path that the TLS volume is mounted at in the benchmark container.
+	tlsMountPath = "/etc/apachebench/tls"
 )
 
-// addJobResultsToStatus will add the output from each Job pod to the ApacheBench status.
-func (r *ReconcileApacheBench) addJobResultsToStatus(cr *v1a1.ApacheBench, job *batchv1.Job) error {
+// addJobResultsToStatus will store the output from each successful Job pod in ConfigMaps, and add a reference to the
+// output along with the parsed report to the ApacheBench status.
+func (r *ReconcileApacheBench) addJobResultsToStatus(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, job *batchv1.Job) error {
 	clientset, err := kubernetes.NewForConfig(r.config)
 	if err != nil {
 		return err
 	}
 
-	opts := metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("job-name=%s", job.Name),
-	}
-
-	podList, err := clientset.CoreV1().Pods(job.Namespace).List(opts)
+	pods, err := r.getJobPods(job)
 	if err != nil {
 		return err
 	}
 
-	results := make([]string, 0)
-	for _, pod := range podList.Items {
+	refs := make([]v1a1.ApacheBenchResultsReference, 0)
+	endpointSummaries := make([][]v1a1.ApacheBenchSummary, len(cr.Spec.Endpoints))
+	stageSummaries := make([][]v1a1.ApacheBenchSummary, len(cr.Spec.Stages))
+	summaries := make([]v1a1.ApacheBenchSummary, 0)
+	times := make([]float64, 0)
+	timedPods := 0
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodSucceeded {
+			continue // Only successful pods have a complete report
+		}
+
 		logs, err := r.getPodLogs(clientset, pod)
 		if err != nil {
 			return err
 		}
-		results = append(results, string(logs))
+
+		if len(logs) >= maxPodLogsSize {
+			cr.Status.AddError(fmt.Sprintf("the logs of pod '%s' were truncated at %d bytes", pod.Name, maxPodLogsSize))
+		}
+
+		output, sections := splitOutputSections(string(logs))
+		for _, section := range []string{csvSection, gnuplotSection} {
+			if size, ok := sections[section+skippedSectionSuffix]; ok {
+				cr.Status.AddError(fmt.Sprintf("the %s file of pod '%s' is %s bytes, which is above the limit of %d "+
+					"bytes for the pod logs, set spec.output.volumeClaim to keep it", section, pod.Name,
+					strings.TrimSpace(size), maxFileSectionSize))
+			}
+		}
+		stageOutputs := getStageOutputs(cr, sections)
+		if len(stageOutputs) > 0 {
+			output = joinStageOutputs(stageOutputs)
+		}
+		endpointOutputs := getEndpointOutputs(cr, sections)
+		if len(endpointOutputs) > 0 {
+			output = joinEndpointOutputs(endpointOutputs)
+		}
+
+		ref, err := r.storeResults(cr, run, job, pod, output, sections)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, *ref)
+
+		if cr.Spec.HTML.Enabled {
+			continue // The HTML report is not parsed
+		}
+
+		for i, stageOutput := range stageOutputs {
+			summary, err := parseResults(stageOutput)
+			if err != nil {
+				cr.Status.AddError(fmt.Sprintf("unable to parse results for stage %d of pod '%s': %v", i+1, pod.Name, err))
+				continue
+			}
+			summary.Pod = pod.Name
+			stageSummaries[i] = append(stageSummaries[i], *summary)
+		}
+
+		podEndpointSummaries := make([]v1a1.ApacheBenchSummary, 0)
+		for i, endpointOutput := range endpointOutputs {
+			summary, err := parseResults(endpointOutput)
+			if err != nil {
+				cr.Status.AddError(fmt.Sprintf("unable to parse results for endpoint '%s' of pod '%s': %v",
+					cr.Spec.Endpoints[i].Name, pod.Name, err))
+				continue
+			}
+			summary.Pod = pod.Name
+			endpointSummaries[i] = append(endpointSummaries[i], *summary)
+			podEndpointSummaries = append(podEndpointSummaries, *summary)
+		}
+
+		// The results for the final stage are used as the results for the pod, or the results for every endpoint
+		// combined when there are endpoints.
+		if len(stageOutputs) > 0 {
+			output = stageOutputs[len(stageOutputs)-1]
+		}
+
+		var summary *v1a1.ApacheBenchSummary
+		if len(endpointOutputs) > 0 {
+			if len(podEndpointSummaries) < len(endpointOutputs) {
+				cr.Status.AddError(fmt.Sprintf("unable to parse results for %d of %d endpoint(s) of pod '%s'",
+					len(endpointOutputs)-len(podEndpointSummaries), len(endpointOutputs), pod.Name))
+				continue
+			}
+			summary = combineEndpointSummaries(cr, podEndpointSummaries)
+		} else if summary, err = parseResults(output); err != nil {
+			cr.Status.AddError(fmt.Sprintf("unable to parse results for pod '%s': %v", pod.Name, err))
+			continue
+		}
+		summary.Pod = pod.Name
+		summaries = append(summaries, *summary)
+
+		if gnuplot, ok := sections[gnuplotSection]; ok {
+			podTimes, err := parseGnuplot(gnuplot)
+			if err != nil {
+				cr.Status.AddError(fmt.Sprintf("unable to parse gnuplot data for pod '%s': %v", pod.Name, err))
+				continue
+			}
+			times = append(times, podTimes...)
+			timedPods++
+		}
+	}
+	cr.Status.Endpoints = newEndpointResults(cr, run, endpointSummaries)
+	cr.Status.Results = nil
+	cr.Status.ResultsRefs = refs
+	cr.Status.Stages = newStageResults(cr, stageSummaries)
+	cr.Status.Summary = summaries
+	cr.Status.Aggregate = aggregateSummaries(summaries)
+
+	// The timings for every request give exact percentiles for the combined results, rather than an estimate.
+	if cr.Status.Aggregate != nil && timedPods == len(summaries) && len(times) > 0 {
+		cr.Status.Aggregate.Percentiles = getPercentiles(times)
 	}
-	cr.Status.Results = results
 
 	return nil
 }
 
-// addStatusError will add the given error message to the Status.Errors property on the given ApacheBench.
-// The value will not be added if it already exists.
-func addStatusError(cr *v1a1.ApacheBench, msg string) {
-	found := false
+// jobCreationError is returned when the Job for the current run cannot be created because of the spec or a referenced
+// object. The run has already been marked as failed.
+type jobCreationError struct {
+	msg string
+}
+
+// Error will return the message for the jobCreationError.
+func (e *jobCreationError) Error() string {
+	return e.msg
+}
+
+// createJob will create the Job for the given run of the given ApacheBench.
+// The Job is not created again if it already exists, or until the Target and readiness checks are ready when set.
+func (r *ReconcileApacheBench) createJob(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) error {
+	job := newJob(cr, run.Job)
+	job.Annotations = map[string]string{
+		specHashAnnotation: run.SpecHash,
+	}
+	if len(run.RunID) > 0 {
+		job.Annotations[v1a1.ApacheBenchRunIDAnnotation] = run.RunID
+	}
 
-	for _, s := range cr.Status.Errors {
-		if s == msg {
-			found = true
-			break
+	if cr.Spec.Job != nil {
+		job.Spec = *cr.Spec.Job
+	}
+
+	url, waiting, err := r.getTargetURL(cr)
+	if err != nil {
+		return err
+	}
+	if len(waiting) <= 0 && !isSearchInProgress(run) {
+		if waiting, err = r.getReadinessNotReadyReason(cr); err != nil {
+			return err
 		}
 	}
+	if len(waiting) > 0 {
+		waitForReadiness(cr, run, waiting)
+		return nil
+	}
+	run.URL = url
+
+	if cr.Spec.Output != nil && cr.Spec.Output.VolumeClaim != nil {
+		run.VolumePath = getVolumePath(cr, run)
+	}
+
+	template, err := r.newPodTemplateSpec(cr, run)
+	if err != nil {
+		return err
+	}
+	job.Spec.Template = *template
+
+	if err := controllerutil.SetControllerReference(cr, job, r.scheme); err != nil {
+		return err
+	}
 
-	if !found {
-		cr.Status.Errors = append(cr.Status.Errors, msg)
+	err = r.client.Create(context.TODO(), job)
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
 	}
+
+	setCondition(cr, v1a1.ApacheBenchConditionJobCreated, corev1.ConditionTrue, "JobCreated",
+		fmt.Sprintf("created job '%s' for run %d", job.Name, run.Number))
+	return nil
 }
 
 // fetchObject will retrieve the object with the given namespace and name using the Kubernetes API.
@@ -90,29 +267,62 @@ func (r *ReconcileApacheBench) fetchObject(namespace string, name string, obj ru
 	return r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, obj)
 }
 
-// getCommand will return the command to execute for the given ApacheBench.
-func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench) ([]string, error) {
+// failJobCreation will mark the current run of the given ApacheBench as failed using the given reason and message,
+// when the Job for the run cannot be created because of the spec or a referenced object. A jobCreationError is
+// returned with the given message, so that the request is not retried until the spec or a referenced object changes.
+func (r *ReconcileApacheBench) failJobCreation(cr *v1a1.ApacheBench, reason string, msg string) error {
+	if run := cr.GetCurrentRun(); run != nil {
+		run.Phase = v1a1.ApacheBenchPhaseFailed
+		if run.CompletionTime == nil {
+			now := metav1.Now()
+			run.CompletionTime = &now
+		}
+	}
+	cr.Status.Phase = v1a1.ApacheBenchPhaseFailed
+
+	log.Info("unable to create job", "namespace", cr.Namespace, "name", cr.Name, "reason", reason, "message", msg)
+	cr.Status.AddError(msg)
+	setCondition(cr, v1a1.ApacheBenchConditionJobCreated, corev1.ConditionFalse, reason, msg)
+	setCondition(cr, v1a1.ApacheBenchConditionRunning, corev1.ConditionFalse, reason, msg)
+	setCondition(cr, v1a1.ApacheBenchConditionSucceeded, corev1.ConditionFalse, reason, msg)
+	setCondition(cr, v1a1.ApacheBenchConditionFailed, corev1.ConditionTrue, reason, msg)
+	if err := r.client.Status().Update(context.TODO(), cr); err != nil {
+		return err
+	}
+	return &jobCreationError{msg: msg}
+}
+
+// getCommand will return the command to execute for the given run of the given ApacheBench.
+func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) ([]string, error) {
 	cmd := make([]string, 0)
 	cmd = append(cmd, "ab")
 
+	// The credentials are not added to the command, they are passed to the script using environment variables that
+	// reference the Secret.
 	if cr.Spec.Authenticate {
-		user, pass, err := r.getCredentialsFromSecret(cr, "request.username", "request.password")
-		if err != nil {
+		if err := r.validateSecretKeys(cr, "CredentialsNotFound", requestUsernameKey, requestPasswordKey); err != nil {
 			return nil, err
 		}
-
-		cmd = append(cmd, "-A")
-		cmd = append(cmd, fmt.Sprintf("%s:%s", user, pass))
 	}
 
 	if cr.Spec.AuthenticateProxy {
-		user, pass, err := r.getCredentialsFromSecret(cr, "proxy.username", "proxy.password")
-		if err != nil {
+		if err := r.validateSecretKeys(cr, "CredentialsNotFound", proxyUsernameKey, proxyPasswordKey); err != nil {
 			return nil, err
 		}
+	}
 
-		cmd = append(cmd, "-P")
-		cmd = append(cmd, fmt.Sprintf("%s:%s", user, pass))
+	// The cookies and headers from Secret and ConfigMap keys are also passed to the script using environment
+	// variables.
+	if err := r.validateReferences(cr); err != nil {
+		return nil, err
+	}
+
+	if err := r.validateCapacitySearch(cr); err != nil {
+		return nil, err
+	}
+
+	if err := r.validateEndpoints(cr, run); err != nil {
+		return nil, err
 	}
 
 	for key, val := range cr.Spec.Cookies {
@@ -120,12 +330,19 @@ func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench) ([]string, error
 		cmd = append(cmd, fmt.Sprintf("%s=%s", key, val))
 	}
 
-	if cr.Spec.Concurrency > 1 {
+	// The concurrency, requests and time limit are set by the script for each stage or endpoint when there are stages
+	// or endpoints, and the concurrency is set for each iteration of a capacity search. The request options and URL
+	// are also set by the script for each endpoint.
+	endpoints := len(cr.Spec.Endpoints) > 0
+	stages := len(cr.Spec.Stages) > 0 || endpoints
+	concurrency, requests := getLoad(cr, run)
+
+	if concurrency > 1 && !stages {
 		cmd = append(cmd, "-c")
-		cmd = append(cmd, strconv.FormatUint(uint64(cr.Spec.Concurrency), 10))
+		cmd = append(cmd, strconv.FormatUint(uint64(concurrency), 10))
 	}
 
-	if len(cr.Spec.ContentType) > 0 {
+	if len(cr.Spec.ContentType) > 0 && !endpoints {
 		cmd = append(cmd, "-T")
 		cmd = append(cmd, cr.Spec.ContentType)
 	}
@@ -154,9 +371,11 @@ func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench) ([]string, error
 		cmd = append(cmd, "-i")
 	}
 
-	for key, val := range cr.Spec.Headers {
-		cmd = append(cmd, "-H")
-		cmd = append(cmd, fmt.Sprintf("%s=%s", key, val))
+	if !endpoints {
+		for key, val := range cr.Spec.Headers {
+			cmd = append(cmd, "-H")
+			cmd = append(cmd, fmt.Sprintf("%s: %s", key, val))
+		}
 	}
 
 	if cr.Spec.HTML.Enabled {
@@ -178,7 +397,7 @@ func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench) ([]string, error
 		}
 	}
 
-	if len(cr.Spec.HTTPMethod) > 0 {
+	if len(cr.Spec.HTTPMethod) > 0 && !endpoints {
 		cmd = append(cmd, "-m")
 		cmd = append(cmd, cr.Spec.HTTPMethod)
 	}
@@ -187,7 +406,7 @@ func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench) ([]string, error
 		cmd = append(cmd, "-k")
 	}
 
-	if len(cr.Spec.POSTDataKey) > 0 {
+	if len(cr.Spec.POSTDataKey) > 0 && !endpoints {
 		cmd = append(cmd, "-p")
 		cmd = append(cmd, fmt.Sprintf("/data/%s", cr.Spec.POSTDataKey))
 	}
@@ -197,17 +416,17 @@ func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench) ([]string, error
 		cmd = append(cmd, cr.Spec.Proxy)
 	}
 
-	if len(cr.Spec.PUTDataKey) > 0 {
+	if len(cr.Spec.PUTDataKey) > 0 && !endpoints {
 		cmd = append(cmd, "-u")
 		cmd = append(cmd, fmt.Sprintf("/data/%s", cr.Spec.PUTDataKey))
 	}
 
-	if cr.Spec.Requests > 1 {
+	if requests > 1 && !stages {
 		cmd = append(cmd, "-n")
-		cmd = append(cmd, strconv.FormatUint(uint64(cr.Spec.Requests), 10))
+		cmd = append(cmd, strconv.FormatUint(uint64(requests), 10))
 	}
 
-	if cr.Spec.TimeLimit > 0 {
+	if cr.Spec.TimeLimit > 0 && !stages {
 		cmd = append(cmd, "-t")
 		cmd = append(cmd, strconv.FormatUint(uint64(cr.Spec.TimeLimit), 10))
 	}
@@ -217,6 +436,15 @@ func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench) ([]string, error
 		cmd = append(cmd, cr.Spec.TLS.CipherSuite)
 	}
 
+	if len(cr.Spec.TLS.ClientCertificateKey) > 0 {
+		if err := r.validateSecretKeys(cr, "ClientCertificateNotFound", cr.Spec.TLS.ClientCertificateKey); err != nil {
+			return nil, err
+		}
+
+		cmd = append(cmd, "-E")
+		cmd = append(cmd, path.Join(tlsMountPath, clientCertificateFile))
+	}
+
 	if len(cr.Spec.TLS.Protocol) > 0 {
 		cmd = append(cmd, "-f")
 		cmd = append(cmd, cr.Spec.TLS.Protocol)
@@ -232,10 +460,26 @@ func (r *ReconcileApacheBench) getCommand(cr *v1a1.ApacheBench) ([]string, error
 		cmd = append(cmd, strconv.FormatUint(uint64(cr.Spec.WindowSize), 10))
 	}
 
-	cmd = append(cmd, cr.Spec.URL)
+	if !endpoints {
+		cmd = append(cmd, run.URL)
+	}
 	return cmd, nil
 }
 
+// getJobPods will return the Pods that were created for the given Job.
+func (r *ReconcileApacheBench) getJobPods(job *batchv1.Job) ([]corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	opts := []client.ListOption{
+		client.InNamespace(job.Namespace),
+		client.MatchingLabels{"job-name": job.Name},
+	}
+
+	if err := r.client.List(context.TODO(), podList, opts...); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
 // getImage will return the container image to use for the given ApacheBench.
 func getImage(cr *v1a1.ApacheBench) string {
 	img := cr.Spec.Image
@@ -245,42 +489,33 @@ func getImage(cr *v1a1.ApacheBench) string {
 	return img
 }
 
-// getCredentialsFromSecret will return credential values using the given keys for the ApacheBench CR.
-func (r *ReconcileApacheBench) getCredentialsFromSecret(cr *v1a1.ApacheBench, userKey string, passKey string) ([]byte, []byte, error) {
-	secret := newSecret(cr)
-	var failed = false
-
-	if r.isObjectFound(cr.Namespace, secret.Name, secret) {
-		_, ok := secret.Data[userKey]
-		if !ok {
-			failed = true
-			addStatusError(cr, fmt.Sprintf("unable to locate username key '%s' in secret '%s'", userKey, secret.Name))
-		}
+// getCredentialsEnv will return the environment variables for the benchmark container that contain the credentials
+// for the given ApacheBench. The values are referenced from the Secret, so that they do not appear in the Pod spec.
+func getCredentialsEnv(cr *v1a1.ApacheBench) []corev1.EnvVar {
+	env := make([]corev1.EnvVar, 0)
 
-		_, ok = secret.Data[passKey]
-		if !ok {
-			failed = true
-			addStatusError(cr, fmt.Sprintf("unable to locate password key '%s' in secret '%s'", passKey, secret.Name))
-		}
-	} else {
-		failed = true
-		addStatusError(cr, fmt.Sprintf("unable to locate secret '%s'", secret.Name))
+	if cr.Spec.Authenticate {
+		env = append(env,
+			newSecretEnvVar(cr, authUsernameEnv, requestUsernameKey),
+			newSecretEnvVar(cr, authPasswordEnv, requestPasswordKey),
+		)
 	}
 
-	if failed {
-		cr.Status.Phase = "Failed"
-		if e := r.client.Status().Update(context.TODO(), cr); e != nil {
-			return nil, nil, e
-		}
-		return nil, nil, errors.New("unable to locate credentials")
+	if cr.Spec.AuthenticateProxy {
+		env = append(env,
+			newSecretEnvVar(cr, proxyUsernameEnv, proxyUsernameKey),
+			newSecretEnvVar(cr, proxyPasswordEnv, proxyPasswordKey),
+		)
 	}
 
-	return secret.Data[userKey], secret.Data[passKey], nil
+	return env
 }
 
-// getPodLogs will return the log output in bytes for the given Pod.
+// getPodLogs will return the log output in bytes from the benchmark container in the given Pod. At most
+// maxPodLogsSize bytes are read.
 func (r *ReconcileApacheBench) getPodLogs(clientset *kubernetes.Clientset, pod corev1.Pod) ([]byte, error) {
-	opts := corev1.PodLogOptions{} // Set size limit on result?
+	limit := int64(maxPodLogsSize)
+	opts := corev1.PodLogOptions{Container: benchmarkContainerName, LimitBytes: &limit}
 
 	req := clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &opts)
 	logs, err := req.Stream()
@@ -302,13 +537,29 @@ func (r *ReconcileApacheBench) getPodLogs(clientset *kubernetes.Clientset, pod c
 func getVolumeMounts(cr *v1a1.ApacheBench) []corev1.VolumeMount {
 	vms := make([]corev1.VolumeMount, 0)
 
-	if len(cr.Spec.POSTDataKey) < 0 || len(cr.Spec.PUTDataKey) < 0 {
+	if usesDataVolume(cr) {
 		vms = append(vms, corev1.VolumeMount{
 			Name:      "data",
 			MountPath: "/data",
 		})
 	}
 
+	if len(cr.Spec.TLS.ClientCertificateKey) > 0 {
+		vms = append(vms, corev1.VolumeMount{
+			Name:      "tls",
+			MountPath: tlsMountPath,
+			ReadOnly:  true,
+		})
+	}
+
+	if cr.Spec.Output != nil && cr.Spec.Output.VolumeClaim != nil {
+		vms = append(vms, corev1.VolumeMount{
+			Name:      "results",
+			MountPath: resultsMountPath,
+			SubPath:   cr.Spec.Output.VolumeClaim.SubPath,
+		})
+	}
+
 	return vms
 }
 
@@ -316,7 +567,7 @@ func getVolumeMounts(cr *v1a1.ApacheBench) []corev1.VolumeMount {
 func getVolumes(cr *v1a1.ApacheBench) []corev1.Volume {
 	vs := make([]corev1.Volume, 0)
 
-	if len(cr.Spec.POSTDataKey) < 0 || len(cr.Spec.PUTDataKey) < 0 {
+	if usesDataVolume(cr) {
 		vs = append(vs, corev1.Volume{
 			Name: "data",
 			VolumeSource: corev1.VolumeSource{
@@ -329,6 +580,32 @@ func getVolumes(cr *v1a1.ApacheBench) []corev1.Volume {
 		})
 	}
 
+	if len(cr.Spec.TLS.ClientCertificateKey) > 0 {
+		vs = append(vs, corev1.Volume{
+			Name: "tls",
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName: cr.Spec.SecretName,
+					Items: []corev1.KeyToPath{{
+						Key:  cr.Spec.TLS.ClientCertificateKey,
+						Path: clientCertificateFile,
+					}},
+				},
+			},
+		})
+	}
+
+	if cr.Spec.Output != nil && cr.Spec.Output.VolumeClaim != nil {
+		vs = append(vs, corev1.Volume{
+			Name: "results",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: cr.Spec.Output.VolumeClaim.ClaimName,
+				},
+			},
+		})
+	}
+
 	return vs
 }
 
@@ -341,51 +618,78 @@ func (r *ReconcileApacheBench) isObjectFound(namespace string, name string, obj
 	return true
 }
 
-// newJob returns a new Job instance for the given ApacheBench.
-func newJob(cr *v1a1.ApacheBench) *batchv1.Job {
+// newJob returns a new Job instance with the given name for the given ApacheBench.
+func newJob(cr *v1a1.ApacheBench, name string) *batchv1.Job {
 	return &batchv1.Job{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      cr.Name,
+			Name:      name,
 			Namespace: cr.Namespace,
 			Labels:    cr.Labels,
 		},
 	}
 }
 
-// newPodSpec returns a new PodSpec for the given ApacheBench.
-func (r *ReconcileApacheBench) newPodSpec(cr *v1a1.ApacheBench) (*corev1.PodSpec, error) {
-	cmd, err := r.getCommand(cr)
+// newPodSpec returns a new PodSpec for the given run of the given ApacheBench.
+func (r *ReconcileApacheBench) newPodSpec(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) (*corev1.PodSpec, error) {
+	cmd, err := r.getCommand(cr, run)
 	if err != nil {
 		return nil, err
 	}
 
+	env := make([]corev1.EnvVar, 0)
+	if cr.Spec.Output != nil && cr.Spec.Output.VolumeClaim != nil {
+		env = append(env, corev1.EnvVar{Name: resultsDirEnv, Value: getResultsDir(cr, run)})
+	}
+	env = append(env, getCredentialsEnv(cr)...)
+	env = append(env, getReferenceEnv(cr)...)
+
 	pod := corev1.PodSpec{
 		Containers: []corev1.Container{{
-			Command:         cmd,
+			Command:         getContainerCommand(cr, run, cmd),
+			Env:             env,
 			Image:           getImage(cr),
 			ImagePullPolicy: corev1.PullIfNotPresent,
-			Name:            "benchmark",
+			Name:            benchmarkContainerName,
 			VolumeMounts:    getVolumeMounts(cr),
 		}},
 		RestartPolicy: corev1.RestartPolicyOnFailure,
 		Volumes:       getVolumes(cr),
 	}
 
+	// The probe is only needed before the first Job of a capacity search, as for the other readiness checks.
+	if cr.Spec.Readiness != nil && cr.Spec.Readiness.HTTPGet != nil && !isSearchInProgress(run) {
+		pod.InitContainers = append(pod.InitContainers, newReadinessContainer(cr, run))
+	}
+
+	if cr.Spec.Warmup != nil {
+		warmup, err := r.newWarmupContainer(cr, run)
+		if err != nil {
+			return nil, err
+		}
+		pod.InitContainers = append(pod.InitContainers, *warmup)
+	}
+
 	return &pod, nil
 }
 
-// newPodTemplateSpec returns a new PodTemplateSpec for the given ApacheBench.
-func (r *ReconcileApacheBench) newPodTemplateSpec(cr *v1a1.ApacheBench) (*corev1.PodTemplateSpec, error) {
-	podSpec, err := r.newPodSpec(cr)
+// newPodTemplateSpec returns a new PodTemplateSpec for the given run of the given ApacheBench.
+func (r *ReconcileApacheBench) newPodTemplateSpec(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) (*corev1.PodTemplateSpec, error) {
+	podSpec, err := r.newPodSpec(cr, run)
 	if err != nil {
 		return nil, err
 	}
 
+	labels := make(map[string]string)
+	for key, val := range cr.Labels {
+		labels[key] = val
+	}
+	labels[apacheBenchLabel] = cr.Name
+
 	ts := corev1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      cr.Name,
 			Namespace: cr.Namespace,
-			Labels:    cr.Labels,
+			Labels:    labels,
 		},
 		Spec: *podSpec,
 	}
@@ -403,36 +707,156 @@ func newSecret(cr *v1a1.ApacheBench) *corev1.Secret {
 	}
 }
 
-// reconcileJob will ensure that the Job for the given ApacheBench is present.
+// newSecretEnvVar returns a new EnvVar with the given name, that references the given key in the Secret for the given
+// ApacheBench.
+func newSecretEnvVar(cr *v1a1.ApacheBench, name string, key string) corev1.EnvVar {
+	return corev1.EnvVar{
+		Name: name,
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: cr.Spec.SecretName,
+				},
+				Key: key,
+			},
+		},
+	}
+}
+
+// reconcileJobs will ensure that the Job for the current run of the given ApacheBench is present, starting a new run
+// when one is needed.
 func (r *ReconcileApacheBench) reconcileJobs(cr *v1a1.ApacheBench) error {
-	job := newJob(cr)
-	if r.isObjectFound(cr.Namespace, job.Name, job) {
-		if job.Status.Succeeded > 0 && job.Status.Succeeded == *job.Spec.Parallelism && cr.Status.Phase != string(batchv1.JobComplete) {
-			// Mark status Phase as Complete
-			cr.Status.Phase = string(batchv1.JobComplete)
-
-			// Add results to the CR status
-			if err := r.addJobResultsToStatus(cr, job); err != nil {
+	references, err := r.getReferencedData(cr)
+	if err != nil {
+		return err
+	}
+	specHash := getSpecHash(cr, references)
+
+	trigger, scheduled, err := getRunTrigger(cr, specHash, time.Now())
+	if err != nil {
+		cr.Status.AddError(fmt.Sprintf("invalid schedule: %v", err))
+		return r.client.Status().Update(context.TODO(), cr)
+	}
+
+	if len(trigger) > 0 {
+		return r.startRun(cr, trigger, scheduled, specHash)
+	}
+
+	run := cr.GetCurrentRun()
+	if run == nil {
+		return nil // Nothing to do until the first run
+	}
+
+	status := cr.Status.DeepCopy()
+	if !isRunFinished(run) {
+		job := newJob(cr, run.Job)
+		if !r.isObjectFound(cr.Namespace, job.Name, job) {
+			if err := r.createJob(cr, run); err != nil {
 				return err
 			}
+		} else if err := r.updateRunStatus(cr, run, job); err != nil {
+			return err
+		}
+	} else if isJobCreationFailed(cr) {
+		// A change to the spec starts a new run, while a change to a referenced object is handled here.
+		if err := r.retryJobCreation(cr, run); err != nil {
+			return err
+		}
+	}
+
+	// The verdict and comparison are evaluated on every pass, so that changes to the thresholds or baseline apply to
+	// the current results.
+	updateVerdict(cr)
+	r.updateComparison(cr)
+
+	// A failed output is returned once the status is updated, so that the output is retried with backoff.
+	outputErr := r.reconcileOutputs(cr)
 
-			return r.client.Status().Update(context.TODO(), cr)
+	if !reflect.DeepEqual(status, &cr.Status) {
+		if err := r.client.Status().Update(context.TODO(), cr); err != nil {
+			return err
 		}
-		return nil // Job not complete, move along...
 	}
+	return outputErr
+}
 
-	if cr.Spec.Job != nil {
-		job.Spec = *cr.Spec.Job
+// isJobCreationError will return true if the given error was returned because the Job for the current run could not
+// be created, in which case the run has already been marked as failed.
+func isJobCreationError(err error) bool {
+	_, ok := err.(*jobCreationError)
+	return ok
+}
+
+// isJobCreationFailed will return true if the current run of the given ApacheBench failed because the Job could not
+// be created, rather than because the Job failed or the readiness checks did not pass in time.
+func isJobCreationFailed(cr *v1a1.ApacheBench) bool {
+	run := cr.GetCurrentRun()
+	if run == nil || run.Phase != v1a1.ApacheBenchPhaseFailed {
+		return false
 	}
 
-	template, err := r.newPodTemplateSpec(cr)
-	if err != nil {
+	created := getCondition(cr, v1a1.ApacheBenchConditionJobCreated)
+	failed := getCondition(cr, v1a1.ApacheBenchConditionFailed)
+	return created != nil && created.Status == corev1.ConditionFalse &&
+		failed != nil && failed.Status == corev1.ConditionTrue && failed.Reason != readinessTimeoutReason
+}
+
+// retryJobCreation will try again to create the Job for the given run of the given ApacheBench, which failed because
+// of a referenced object, eg. a missing Secret key. The run continues once the Job is created, otherwise the run
+// remains failed without any change to the status.
+func (r *ReconcileApacheBench) retryJobCreation(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) error {
+	run.Phase = v1a1.ApacheBenchPhasePending
+	if err := r.createJob(cr, run); err != nil {
 		return err
 	}
-	job.Spec.Template = *template
 
-	if err := controllerutil.SetControllerReference(cr, job, r.scheme); err != nil {
-		return err
+	log.Info("retried job creation", "namespace", cr.Namespace, "name", cr.Name, "run", run.Number)
+	run.CompletionTime = nil
+	cr.Status.Errors = nil
+	cr.Status.Phase = run.Phase
+	setCondition(cr, v1a1.ApacheBenchConditionFailed, corev1.ConditionFalse, "JobCreationRetried",
+		fmt.Sprintf("retried job creation for run %d", run.Number))
+	return nil
+}
+
+// usesDataVolume will return true if the ConfigMap specified in the ConfigMapName property of the given ApacheBench
+// is needed for POST or PUT data, for the benchmark or any endpoint.
+func usesDataVolume(cr *v1a1.ApacheBench) bool {
+	if len(cr.Spec.POSTDataKey) > 0 || len(cr.Spec.PUTDataKey) > 0 {
+		return true
+	}
+
+	for _, endpoint := range cr.Spec.Endpoints {
+		if len(endpoint.POSTDataKey) > 0 || len(endpoint.PUTDataKey) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSecretKeys will check that each of the given keys is present in the Secret that is referenced by the given
+// ApacheBench. If the Secret or any key cannot be located, the ApacheBench is marked as failed using the given reason
+// and an error is returned.
+func (r *ReconcileApacheBench) validateSecretKeys(cr *v1a1.ApacheBench, reason string, keys ...string) error {
+	secret := newSecret(cr)
+	var failed = false
+
+	if r.isObjectFound(cr.Namespace, secret.Name, secret) {
+		for _, key := range keys {
+			if _, ok := secret.Data[key]; !ok {
+				failed = true
+				cr.Status.AddError(fmt.Sprintf("unable to locate key '%s' in secret '%s'", key, secret.Name))
+			}
+		}
+	} else {
+		failed = true
+		cr.Status.AddError(fmt.Sprintf("unable to locate secret '%s'", secret.Name))
+	}
+
+	if failed {
+		return r.failJobCreation(cr, reason,
+			fmt.Sprintf("unable to locate keys %s in secret '%s'", strings.Join(keys, ", "), secret.Name))
 	}
-	return r.client.Create(context.TODO(), job)
+
+	return nil
 }
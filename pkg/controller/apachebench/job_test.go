@@ -0,0 +1,302 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newTestReconciler will return a ReconcileApacheBench that uses a fake client containing the given objects.
+func newTestReconciler(t *testing.T, objs ...runtime.Object) *ReconcileApacheBench {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1a1.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return &ReconcileApacheBench{client: fake.NewFakeClientWithScheme(s, objs...), scheme: s}
+}
+
+// serveTestPodLogs will start a stand-in API server that returns the logs for each Pod from the given map by name, and
+// configure the given ReconcileApacheBench to use it. The server must be closed by the caller.
+func serveTestPodLogs(r *ReconcileApacheBench, logs map[string]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// The path is /api/v1/namespaces/<namespace>/pods/<name>/log
+		parts := strings.Split(req.URL.Path, "/")
+		if len(parts) != 8 || parts[7] != "log" {
+			http.NotFound(w, req)
+			return
+		}
+		data, ok := logs[parts[6]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		if limit, err := strconv.Atoi(req.URL.Query().Get("limitBytes")); err == nil && limit < len(data) {
+			data = data[:limit]
+		}
+		_, _ = w.Write([]byte(data))
+	}))
+	r.config = &rest.Config{Host: server.URL}
+	return server
+}
+
+func TestFailJobCreation(t *testing.T) {
+	cr := &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"},
+		Spec: v1a1.ApacheBenchSpec{
+			Authenticate: true,
+			SecretName:   "credentials",
+			URL:          "http://example.com/",
+		},
+	}
+	r := newTestReconciler(t, cr)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}}
+
+	// The Secret is missing, so the run fails without requeuing the request.
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("Reconcile returned an error: %v", err)
+	}
+
+	cr = &v1a1.ApacheBench{}
+	if err := r.client.Get(context.TODO(), req.NamespacedName, cr); err != nil {
+		t.Fatal(err)
+	}
+	run := cr.GetCurrentRun()
+	if run == nil || run.Phase != v1a1.ApacheBenchPhaseFailed || run.CompletionTime == nil {
+		t.Fatalf("expected the run to have failed with a completion time, got %+v", run)
+	}
+	if cond := getCondition(cr, v1a1.ApacheBenchConditionFailed); cond == nil || cond.Status != corev1.ConditionTrue {
+		t.Errorf("expected the Failed condition to be true, got %+v", cond)
+	}
+	if !isJobCreationFailed(cr) {
+		t.Errorf("expected the job creation to have failed")
+	}
+	completion := run.CompletionTime.DeepCopy()
+
+	// Reconciling again without any change leaves the run failed.
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("Reconcile returned an error: %v", err)
+	}
+	cr = &v1a1.ApacheBench{}
+	if err := r.client.Get(context.TODO(), req.NamespacedName, cr); err != nil {
+		t.Fatal(err)
+	}
+	if run = cr.GetCurrentRun(); len(cr.Status.Runs) != 1 || run.Phase != v1a1.ApacheBenchPhaseFailed ||
+		!run.CompletionTime.Equal(completion) {
+		t.Fatalf("expected the run to remain failed, got %+v", cr.Status.Runs)
+	}
+
+	// Once the Secret is created, the Job is created for the same run.
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: cr.Namespace},
+		Data:       map[string][]byte{requestUsernameKey: []byte("user"), requestPasswordKey: []byte("pass")},
+	}
+	if err := r.client.Create(context.TODO(), secret); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(req); err != nil {
+		t.Fatalf("Reconcile returned an error: %v", err)
+	}
+
+	cr = &v1a1.ApacheBench{}
+	if err := r.client.Get(context.TODO(), req.NamespacedName, cr); err != nil {
+		t.Fatal(err)
+	}
+	if run = cr.GetCurrentRun(); len(cr.Status.Runs) != 1 || run.Phase == v1a1.ApacheBenchPhaseFailed ||
+		run.CompletionTime != nil {
+		t.Fatalf("expected the run to continue, got %+v", cr.Status.Runs)
+	}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: cr.Namespace, Name: run.Job}, &batchv1.Job{}); err != nil {
+		t.Errorf("expected the job to be created: %v", err)
+	}
+}
+
+func TestAddJobResultsToStatusLogSize(t *testing.T) {
+	cr := &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"},
+		Spec:       v1a1.ApacheBenchSpec{Gnuplot: true, URL: "http://example.com/"},
+	}
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "example-1", Namespace: cr.Namespace}}
+	objs := []runtime.Object{cr}
+	for _, name := range []string{"example-1-skipped", "example-1-truncated"} {
+		objs = append(objs, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: cr.Namespace, Labels: map[string]string{"job-name": job.Name}},
+			Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+		})
+	}
+	r := newTestReconciler(t, objs...)
+	server := serveTestPodLogs(r, map[string]string{
+		"example-1-skipped":   abHeader + outputSectionPrefix + gnuplotSection + skippedSectionSuffix + outputSectionSuffix + "\n5000000\n",
+		"example-1-truncated": abHeader + strings.Repeat("\n", maxPodLogsSize),
+	})
+	defer server.Close()
+
+	if err := r.addJobResultsToStatus(cr, &v1a1.ApacheBenchRun{Number: 1}, job); err != nil {
+		t.Fatalf("addJobResultsToStatus() returned an error: %v", err)
+	}
+
+	// The results are still collected from both Pods, with an error for each.
+	if len(cr.Status.Summary) != 2 {
+		t.Errorf("expected the results of both pods, got %+v", cr.Status.Summary)
+	}
+	want := []string{
+		"the gnuplot file of pod 'example-1-skipped' is 5000000 bytes, which is above the limit of 4194304 bytes for " +
+			"the pod logs, set spec.output.volumeClaim to keep it",
+		"the logs of pod 'example-1-truncated' were truncated at 8388608 bytes",
+	}
+	if !reflect.DeepEqual(cr.Status.Errors, want) {
+		t.Errorf("errors = %q, want %q", cr.Status.Errors, want)
+	}
+}
+
+func TestNewPodSpecCredentials(t *testing.T) {
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "credentials", Namespace: "benchmark"},
+		Data: map[string][]byte{
+			proxyPasswordKey:   []byte("proxy-secret"),
+			proxyUsernameKey:   []byte("proxy-user"),
+			requestPasswordKey: []byte("request-secret"),
+			requestUsernameKey: []byte("request-user"),
+		},
+	}
+
+	tests := []struct {
+		name    string
+		auth    bool
+		proxy   bool
+		wantEnv map[string]string
+	}{
+		{
+			name:    "request",
+			auth:    true,
+			wantEnv: map[string]string{authPasswordEnv: requestPasswordKey, authUsernameEnv: requestUsernameKey},
+		},
+		{
+			name:    "proxy",
+			proxy:   true,
+			wantEnv: map[string]string{proxyPasswordEnv: proxyPasswordKey, proxyUsernameEnv: proxyUsernameKey},
+		},
+		{
+			name:  "request and proxy",
+			auth:  true,
+			proxy: true,
+			wantEnv: map[string]string{
+				authPasswordEnv:  requestPasswordKey,
+				authUsernameEnv:  requestUsernameKey,
+				proxyPasswordEnv: proxyPasswordKey,
+				proxyUsernameEnv: proxyUsernameKey,
+			},
+		},
+		{
+			name:    "none",
+			wantEnv: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1a1.ApacheBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"},
+				Spec: v1a1.ApacheBenchSpec{
+					Authenticate:      tt.auth,
+					AuthenticateProxy: tt.proxy,
+					Proxy:             "proxy.example.com:3128",
+					Readiness:         &v1a1.ApacheBenchReadinessSpec{HTTPGet: &v1a1.ApacheBenchHTTPProbeSpec{}},
+					SecretName:        secret.Name,
+					URL:               "http://example.com/",
+					Warmup:            &v1a1.ApacheBenchWarmupSpec{Requests: 10},
+				},
+			}
+			run := &v1a1.ApacheBenchRun{Number: 1, URL: cr.Spec.URL}
+			r := newTestReconciler(t, cr, secret)
+
+			pod, err := r.newPodSpec(cr, run)
+			if err != nil {
+				t.Fatalf("newPodSpec() returned an error: %v", err)
+			}
+			if len(pod.InitContainers) != 2 {
+				t.Fatalf("newPodSpec() init containers = %+v, want readiness and warmup", pod.InitContainers)
+			}
+
+			for _, container := range append(pod.InitContainers, pod.Containers...) {
+				// The credentials never appear in the Pod spec.
+				for _, value := range secret.Data {
+					for _, arg := range container.Command {
+						if strings.Contains(arg, string(value)) {
+							t.Errorf("%s container command contains the credential %q:\n%s", container.Name, value, arg)
+						}
+					}
+					for _, env := range container.Env {
+						if strings.Contains(env.Value, string(value)) {
+							t.Errorf("%s container env %s contains the credential %q", container.Name, env.Name, value)
+						}
+					}
+				}
+
+				// The credentials are only referenced from the Secret, by the containers that run the benchmark.
+				gotEnv := make(map[string]string)
+				for _, env := range container.Env {
+					if !strings.HasPrefix(env.Name, "AB_AUTH_") && !strings.HasPrefix(env.Name, "AB_PROXY_") {
+						continue
+					}
+					if env.ValueFrom == nil || env.ValueFrom.SecretKeyRef == nil || env.ValueFrom.SecretKeyRef.Name != secret.Name {
+						t.Errorf("%s container env %s = %+v, want a reference to Secret %s", container.Name, env.Name, env, secret.Name)
+						continue
+					}
+					gotEnv[env.Name] = env.ValueFrom.SecretKeyRef.Key
+				}
+				wantEnv := tt.wantEnv
+				if container.Name == readinessContainerName {
+					wantEnv = map[string]string{}
+				}
+				if !reflect.DeepEqual(gotEnv, wantEnv) {
+					t.Errorf("%s container credentials env = %v, want %v", container.Name, gotEnv, wantEnv)
+				}
+
+				// The script passes the credentials to ab from the environment.
+				script := strings.Join(container.Command, "\n")
+				if tt.auth && container.Name != readinessContainerName &&
+					!strings.Contains(script, `-A "$AB_AUTH_USERNAME:$AB_AUTH_PASSWORD"`) {
+					t.Errorf("%s container command does not pass the request credentials:\n%s", container.Name, script)
+				}
+				if tt.proxy && container.Name != readinessContainerName &&
+					!strings.Contains(script, `-P "$AB_PROXY_USERNAME:$AB_PROXY_PASSWORD"`) {
+					t.Errorf("%s container command does not pass the proxy credentials:\n%s", container.Name, script)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"strconv"
+	"sync"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const (
+	// metricsNamespace is the prefix for the names of the benchmark result metrics.
+	metricsNamespace = "apachebench"
+)
+
+var (
+	// resultMetrics are the benchmark result metrics exported from the operator metrics endpoint.
+	resultMetrics = newResultGauges()
+
+	// exportedRuns tracks the runs for each ApacheBench that have metrics, so that they can be removed later.
+	exportedRuns     = make(map[types.NamespacedName]map[string]bool)
+	exportedRunsLock sync.Mutex
+)
+
+func init() {
+	metrics.Registry.MustRegister(resultMetrics.collectors()...)
+}
+
+// resultGauges is a set of gauges for the results of benchmark runs, labelled by namespace, name and run.
+type resultGauges struct {
+	completeRequests  *prometheus.GaugeVec
+	duration          *prometheus.GaugeVec
+	failedRequests    *prometheus.GaugeVec
+	latency           *prometheus.GaugeVec
+	meanLatency       *prometheus.GaugeVec
+	non2xxResponses   *prometheus.GaugeVec
+	requestsPerSecond *prometheus.GaugeVec
+}
+
+// newResultGauges will return a new set of gauges for the results of benchmark runs.
+func newResultGauges() *resultGauges {
+	runLabels := []string{"namespace", "name", "run"}
+	percentileLabels := []string{"namespace", "name", "run", "quantile"}
+
+	return &resultGauges{
+		completeRequests: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "complete_requests",
+			Help:      "Number of requests completed by the benchmark run.",
+		}, runLabels),
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "duration_seconds",
+			Help:      "Time taken for the benchmark run to complete all requests.",
+		}, runLabels),
+		failedRequests: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "failed_requests",
+			Help:      "Number of failed requests for the benchmark run.",
+		}, runLabels),
+		latency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "latency_milliseconds",
+			Help:      "Percentage of the requests for the benchmark run served within the given time.",
+		}, percentileLabels),
+		meanLatency: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "mean_latency_milliseconds",
+			Help:      "Mean time per request for the benchmark run.",
+		}, runLabels),
+		non2xxResponses: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "non2xx_responses",
+			Help:      "Number of responses with a status code outside of the 2xx range for the benchmark run.",
+		}, runLabels),
+		requestsPerSecond: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "requests_per_second",
+			Help:      "Mean number of requests per second for the benchmark run.",
+		}, runLabels),
+	}
+}
+
+// collectors will return each of the gauges as a collector.
+func (g *resultGauges) collectors() []prometheus.Collector {
+	collectors := []prometheus.Collector{g.latency}
+	for _, gauge := range g.runGauges() {
+		collectors = append(collectors, gauge)
+	}
+	return collectors
+}
+
+// delete will remove the values for the given run of the ApacheBench with the given name.
+func (g *resultGauges) delete(name types.NamespacedName, run string) {
+	labels := prometheus.Labels{"namespace": name.Namespace, "name": name.Name, "run": run}
+	for _, gauge := range g.runGauges() {
+		gauge.Delete(labels)
+	}
+	for quantile := range percentileValues(&v1a1.ApacheBenchPercentiles{}) {
+		labels["quantile"] = quantile
+		g.latency.Delete(labels)
+	}
+}
+
+// runGauges will return each of the gauges, other than the latency percentiles which are also labelled by quantile.
+func (g *resultGauges) runGauges() []*prometheus.GaugeVec {
+	return []*prometheus.GaugeVec{
+		g.completeRequests,
+		g.duration,
+		g.failedRequests,
+		g.meanLatency,
+		g.non2xxResponses,
+		g.requestsPerSecond,
+	}
+}
+
+// set will set the values for the given run of the ApacheBench with the given name from the given summary.
+func (g *resultGauges) set(name types.NamespacedName, run string, summary *v1a1.ApacheBenchSummary) {
+	labels := prometheus.Labels{"namespace": name.Namespace, "name": name.Name, "run": run}
+
+	g.completeRequests.With(labels).Set(float64(summary.CompleteRequests))
+	g.duration.With(labels).Set(summary.TimeTaken)
+	g.failedRequests.With(labels).Set(float64(summary.FailedRequests))
+	g.meanLatency.With(labels).Set(summary.TimePerRequest)
+	g.non2xxResponses.With(labels).Set(float64(summary.Non2xxResponses))
+	g.requestsPerSecond.With(labels).Set(summary.RequestsPerSecond)
+
+	if summary.Percentiles != nil {
+		for quantile, value := range percentileValues(summary.Percentiles) {
+			g.latency.With(prometheus.Labels{
+				"namespace": name.Namespace, "name": name.Name, "run": run, "quantile": quantile,
+			}).Set(value)
+		}
+	}
+}
+
+// deleteMetrics will remove the metrics for all runs of the ApacheBench with the given name.
+func deleteMetrics(name types.NamespacedName) {
+	exportedRunsLock.Lock()
+	defer exportedRunsLock.Unlock()
+
+	for run := range exportedRuns[name] {
+		resultMetrics.delete(name, run)
+	}
+	delete(exportedRuns, name)
+}
+
+// percentileValues will return the given percentiles keyed by quantile.
+func percentileValues(p *v1a1.ApacheBenchPercentiles) map[string]float64 {
+	return map[string]float64{
+		"0.5":  p.P50,
+		"0.66": p.P66,
+		"0.75": p.P75,
+		"0.8":  p.P80,
+		"0.9":  p.P90,
+		"0.95": p.P95,
+		"0.98": p.P98,
+		"0.99": p.P99,
+		"1":    p.P100,
+	}
+}
+
+// updateMetrics will export the results for each completed run of the given ApacheBench, and remove the metrics for
+// any runs that are no longer in the run history.
+func updateMetrics(cr *v1a1.ApacheBench) {
+	name := types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}
+	runs := make(map[string]bool)
+
+	for _, run := range cr.Status.Runs {
+		if run.Phase != v1a1.ApacheBenchPhaseComplete || run.Aggregate == nil {
+			continue
+		}
+
+		number := strconv.Itoa(int(run.Number))
+		resultMetrics.set(name, number, run.Aggregate)
+		runs[number] = true
+	}
+
+	exportedRunsLock.Lock()
+	defer exportedRunsLock.Unlock()
+
+	for run := range exportedRuns[name] {
+		if !runs[run] {
+			resultMetrics.delete(name, run)
+		}
+	}
+	exportedRuns[name] = runs
+}
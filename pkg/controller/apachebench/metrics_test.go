@@ -0,0 +1,117 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"strings"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// requestsPerSecondHeader is the exposition header for the requests per second gauge.
+const requestsPerSecondHeader = `# HELP apachebench_requests_per_second Mean number of requests per second for the benchmark run.
+# TYPE apachebench_requests_per_second gauge
+`
+
+// newMetricsRun will return a completed run with the given number, with results at the given requests per second and
+// with latency percentiles when percentiles is true.
+func newMetricsRun(number int32, requestsPerSecond float64, percentiles bool) v1a1.ApacheBenchRun {
+	run := v1a1.ApacheBenchRun{
+		Aggregate: &v1a1.ApacheBenchSummary{CompleteRequests: 100, RequestsPerSecond: requestsPerSecond},
+		Number:    number,
+		Phase:     v1a1.ApacheBenchPhaseComplete,
+	}
+	if percentiles {
+		run.Aggregate.Percentiles = &v1a1.ApacheBenchPercentiles{P50: 10, P95: 20, P99: 30, P100: 40}
+	}
+	return run
+}
+
+// checkMetrics will check the number of series for the run and latency gauges, and the requests per second series.
+func checkMetrics(t *testing.T, wantRuns int, wantLatencies int, wantRequestsPerSecond string) {
+	t.Helper()
+	for _, gauge := range resultMetrics.runGauges() {
+		if got := testutil.CollectAndCount(gauge); got != wantRuns {
+			t.Errorf("gauge has %d series, want %d", got, wantRuns)
+		}
+	}
+	if got := testutil.CollectAndCount(resultMetrics.latency); got != wantLatencies {
+		t.Errorf("latency gauge has %d series, want %d", got, wantLatencies)
+	}
+
+	want := ""
+	if len(wantRequestsPerSecond) > 0 {
+		want = requestsPerSecondHeader + wantRequestsPerSecond
+	}
+	if err := testutil.CollectAndCompare(resultMetrics.requestsPerSecond, strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUpdateMetrics(t *testing.T) {
+	// The gauges are replaced for the test, so that the series exported by other tests are not counted.
+	defer func(gauges *resultGauges) { resultMetrics = gauges }(resultMetrics)
+	resultMetrics = newResultGauges()
+
+	cr := &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics", Namespace: "benchmark"},
+		Status: v1a1.ApacheBenchStatus{
+			Runs: []v1a1.ApacheBenchRun{
+				newMetricsRun(1, 100, true),
+				{Number: 2, Phase: v1a1.ApacheBenchPhaseFailed, Aggregate: &v1a1.ApacheBenchSummary{RequestsPerSecond: 1}},
+				newMetricsRun(3, 300, false),
+				{Number: 4, Phase: v1a1.ApacheBenchPhaseComplete},
+				{Number: 5, Phase: v1a1.ApacheBenchPhaseRunning},
+			},
+		},
+	}
+	other := &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-other", Namespace: "benchmark"},
+		Status:     v1a1.ApacheBenchStatus{Runs: []v1a1.ApacheBenchRun{newMetricsRun(1, 50, false)}},
+	}
+	defer deleteMetrics(types.NamespacedName{Namespace: other.Namespace, Name: other.Name})
+
+	// Only the completed runs with results are exported.
+	updateMetrics(cr)
+	updateMetrics(other)
+	checkMetrics(t, 3, 9, `apachebench_requests_per_second{name="metrics",namespace="benchmark",run="1"} 100
+apachebench_requests_per_second{name="metrics",namespace="benchmark",run="3"} 300
+apachebench_requests_per_second{name="metrics-other",namespace="benchmark",run="1"} 50
+`)
+	if got := testutil.ToFloat64(resultMetrics.latency.WithLabelValues("benchmark", "metrics", "1", "0.95")); got != 20 {
+		t.Errorf("p95 latency = %v, want 20", got)
+	}
+
+	// The runs trimmed from the history are removed, including their latency percentiles.
+	cr.Status.Runs = []v1a1.ApacheBenchRun{newMetricsRun(3, 300, false), newMetricsRun(6, 600, false)}
+	updateMetrics(cr)
+	checkMetrics(t, 3, 0, `apachebench_requests_per_second{name="metrics",namespace="benchmark",run="3"} 300
+apachebench_requests_per_second{name="metrics",namespace="benchmark",run="6"} 600
+apachebench_requests_per_second{name="metrics-other",namespace="benchmark",run="1"} 50
+`)
+
+	// The metrics for a deleted ApacheBench are removed, and those for other instances are kept.
+	deleteMetrics(types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name})
+	checkMetrics(t, 1, 0, `apachebench_requests_per_second{name="metrics-other",namespace="benchmark",run="1"} 50
+`)
+
+	deleteMetrics(types.NamespacedName{Namespace: other.Namespace, Name: other.Name})
+	checkMetrics(t, 0, 0, "")
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// reconcileOutputs will send the results for the current run of the given ApacheBench to each of the configured
+// outputs. Every output is attempted, and the first error is returned so that the request is retried with backoff.
+func (r *ReconcileApacheBench) reconcileOutputs(cr *v1a1.ApacheBench) error {
+	pushErr := reconcilePushgateway(cr)
+	archiveErr := r.reconcileArchive(cr)
+
+	if pushErr != nil {
+		return pushErr
+	}
+	return archiveErr
+}
+
+// waitForRunCompletion will return true if the given run has completed. Otherwise the condition with the given type is
+// set to show that the given action is waiting for the run, or cannot be performed because the run failed.
+func waitForRunCompletion(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, condType string, action string) bool {
+	switch run.Phase {
+	case v1a1.ApacheBenchPhaseComplete:
+		return true
+	case v1a1.ApacheBenchPhaseFailed:
+		setCondition(cr, condType, corev1.ConditionFalse, "RunFailed",
+			fmt.Sprintf("no results to %s for failed run %d", action, run.Number))
+	default:
+		setCondition(cr, condType, corev1.ConditionUnknown, "RunInProgress",
+			fmt.Sprintf("waiting for run %d to complete", run.Number))
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus/push"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// defaultPushgatewayJob is the job name used to group the pushed metrics when not specified.
+	defaultPushgatewayJob = "apachebench"
+
+	// pushgatewayTimeout is the time to wait for the Pushgateway to accept the metrics.
+	pushgatewayTimeout = 30 * time.Second
+)
+
+// pushResults will push the results for the given run of the given ApacheBench to the given Pushgateway.
+// The metrics for any previous run with the same grouping are replaced.
+func pushResults(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, pg *v1a1.ApacheBenchPushgatewaySpec) error {
+	gauges := newResultGauges()
+	gauges.set(types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}, strconv.Itoa(int(run.Number)), run.Aggregate)
+
+	job := pg.Job
+	if len(job) <= 0 {
+		job = defaultPushgatewayJob
+	}
+
+	// The metrics are grouped by instance, so that each ApacheBench only replaces its own metrics by default. The
+	// reserved labels are skipped, as the push fails when a grouping label is also set on the metrics.
+	pusher := push.New(pg.URL, job).Client(&http.Client{Timeout: pushgatewayTimeout})
+	pusher = pusher.Grouping("instance", fmt.Sprintf("%s/%s", cr.Namespace, cr.Name))
+	for key, value := range pg.GroupingLabels {
+		if !containsString(v1a1.ReservedPushgatewayLabels, key) {
+			pusher = pusher.Grouping(key, value)
+		}
+	}
+
+	for _, c := range gauges.collectors() {
+		pusher = pusher.Collector(c)
+	}
+
+	return pusher.Push()
+}
+
+// reconcilePushgateway will push the results for the current run of the given ApacheBench to the Pushgateway, once
+// the run has completed. The results are only pushed once for each run. An error is returned if the push fails, so
+// that the request is retried with backoff.
+func reconcilePushgateway(cr *v1a1.ApacheBench) error {
+	run := cr.GetCurrentRun()
+	if cr.Spec.Output == nil || cr.Spec.Output.Pushgateway == nil || run == nil {
+		removeCondition(cr, v1a1.ApacheBenchConditionResultsPushed)
+		return nil
+	}
+
+	if run.PushTime != nil {
+		return nil // Already pushed
+	}
+
+	if !waitForRunCompletion(cr, run, v1a1.ApacheBenchConditionResultsPushed, "push") {
+		return nil
+	}
+
+	if run.Aggregate == nil {
+		setCondition(cr, v1a1.ApacheBenchConditionResultsPushed, corev1.ConditionFalse, "ResultsNotAvailable",
+			fmt.Sprintf("no results to push for run %d", run.Number))
+		return nil
+	}
+
+	if err := pushResults(cr, run, cr.Spec.Output.Pushgateway); err != nil {
+		setCondition(cr, v1a1.ApacheBenchConditionResultsPushed, corev1.ConditionFalse, "PushFailed",
+			fmt.Sprintf("unable to push results for run %d: %v", run.Number, err))
+		return err
+	}
+
+	now := metav1.Now()
+	run.PushTime = &now
+	setCondition(cr, v1a1.ApacheBenchConditionResultsPushed, corev1.ConditionTrue, "Pushed",
+		fmt.Sprintf("results for run %d pushed to %s", run.Number, cr.Spec.Output.Pushgateway.URL))
+	return nil
+}
@@ -0,0 +1,160 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestPushResults(t *testing.T) {
+	var method, path string
+	families := make(map[string]*dto.MetricFamily)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.EscapedPath()
+		decoder := expfmt.NewDecoder(r.Body, expfmt.ResponseFormat(r.Header))
+		for {
+			mf := &dto.MetricFamily{}
+			if err := decoder.Decode(mf); err != nil {
+				if err != io.EOF {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				}
+				break
+			}
+			families[mf.GetName()] = mf
+		}
+	}))
+	defer server.Close()
+
+	cr := &v1a1.ApacheBench{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"}}
+	run := &v1a1.ApacheBenchRun{
+		Number: 3,
+		Aggregate: &v1a1.ApacheBenchSummary{
+			CompleteRequests: 200,
+			Percentiles:      &v1a1.ApacheBenchPercentiles{P50: 19},
+		},
+	}
+
+	// The reserved labels are skipped, otherwise the push fails because the metrics already have the labels.
+	pg := &v1a1.ApacheBenchPushgatewaySpec{
+		GroupingLabels: map[string]string{"env": "ci", "namespace": "other", "run": "latest"},
+		URL:            server.URL,
+	}
+	if err := pushResults(cr, run, pg); err != nil {
+		t.Fatalf("pushResults() returned an error: %v", err)
+	}
+
+	if method != http.MethodPut {
+		t.Errorf("method = %s, want PUT", method)
+	}
+
+	// The grouping labels follow the job as name and value pairs, in any order.
+	parts := strings.Split(strings.TrimPrefix(path, "/metrics/"), "/")
+	grouping := make(map[string]string)
+	for i := 0; i+1 < len(parts); i += 2 {
+		grouping[parts[i]] = parts[i+1]
+	}
+	want := map[string]string{
+		"job":             defaultPushgatewayJob,
+		"instance@base64": "YmVuY2htYXJrL2V4YW1wbGU", // benchmark/example
+		"env":             "ci",
+	}
+	if !reflect.DeepEqual(grouping, want) {
+		t.Errorf("grouping = %v from path %s, want %v", grouping, path, want)
+	}
+
+	tests := []struct {
+		name   string
+		labels map[string]string
+		value  float64
+	}{
+		{
+			name:   "apachebench_complete_requests",
+			labels: map[string]string{"name": "example", "namespace": "benchmark", "run": "3"},
+			value:  200,
+		},
+		{
+			name:   "apachebench_latency_milliseconds",
+			labels: map[string]string{"name": "example", "namespace": "benchmark", "quantile": "0.5", "run": "3"},
+			value:  19,
+		},
+	}
+	for _, tt := range tests {
+		if !hasGauge(families[tt.name], tt.labels, tt.value) {
+			t.Errorf("pushed metrics do not contain %s%v %v: %v", tt.name, tt.labels, tt.value, families[tt.name])
+		}
+	}
+}
+
+// TestReconcilePushFailed checks that the results are exported from the operator metrics endpoint when the push
+// fails, while the request is requeued to retry the push.
+func TestReconcilePushFailed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	cr := &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "push-failed", Namespace: "benchmark"},
+		Spec: v1a1.ApacheBenchSpec{
+			Output: &v1a1.ApacheBenchOutputSpec{Pushgateway: &v1a1.ApacheBenchPushgatewaySpec{URL: server.URL}},
+			URL:    "http://example.com/",
+		},
+	}
+	cr.Status.Runs = []v1a1.ApacheBenchRun{{
+		Aggregate: &v1a1.ApacheBenchSummary{CompleteRequests: 200},
+		Number:    1,
+		Phase:     v1a1.ApacheBenchPhaseComplete,
+		SpecHash:  getSpecHash(cr, nil),
+	}}
+	r := newTestReconciler(t, cr)
+	name := types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}
+	defer deleteMetrics(name)
+
+	if _, err := r.Reconcile(reconcile.Request{NamespacedName: name}); err == nil {
+		t.Fatalf("Reconcile() returned no error, want the push error so that the request is requeued")
+	}
+	gauge := resultMetrics.completeRequests.WithLabelValues(cr.Namespace, cr.Name, "1")
+	if got := testutil.ToFloat64(gauge); got != 200 {
+		t.Errorf("apachebench_complete_requests = %v, want 200", got)
+	}
+}
+
+// hasGauge will return true if the given metric family contains a gauge with the given labels and value.
+func hasGauge(mf *dto.MetricFamily, labels map[string]string, value float64) bool {
+	for _, m := range mf.GetMetric() {
+		got := make(map[string]string)
+		for _, l := range m.GetLabel() {
+			got[l.GetName()] = l.GetValue()
+		}
+		if reflect.DeepEqual(got, labels) && m.GetGauge().GetValue() == value {
+			return true
+		}
+	}
+	return false
+}
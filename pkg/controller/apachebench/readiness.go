@@ -0,0 +1,284 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultProbeInterval is the time between attempts of the HTTP probe when not specified.
+	defaultProbeInterval = 5 * time.Second
+
+	// defaultProbeTimeout is the time to wait for a response to the HTTP probe when not specified.
+	defaultProbeTimeout = 5 * time.Second
+
+	// defaultReadinessTimeout is the maximum time to wait for the readiness checks to pass when not specified.
+	defaultReadinessTimeout = 5 * time.Minute
+
+	// readinessContainerName is the name of the init container that runs the HTTP probe before the benchmark.
+	readinessContainerName = "readiness"
+
+	// readinessProbeReason is the reason for the Running condition on an ApacheBench while the HTTP probe is running
+	// in the benchmark Pod.
+	readinessProbeReason = "WaitingForReadiness"
+
+	// readinessTimeoutReason is the reason for the conditions on an ApacheBench when the run fails because the
+	// readiness checks did not pass in time.
+	readinessTimeoutReason = "ReadinessTimeout"
+)
+
+// getDeploymentNotReadyReason will return the reason that the rollout of the Deployment with the given name has not
+// completed, or an empty string if the rollout is complete. The same checks are used as for kubectl rollout status.
+func (r *ReconcileApacheBench) getDeploymentNotReadyReason(namespace string, name string) (string, error) {
+	deploy := &appsv1.Deployment{}
+	if err := r.fetchObject(namespace, name, deploy); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("waiting for deployment '%s' to be created", name), nil
+		}
+		return "", err
+	}
+
+	if deploy.Generation > deploy.Status.ObservedGeneration {
+		return fmt.Sprintf("waiting for deployment '%s' spec update to be observed", name), nil
+	}
+
+	for _, cond := range deploy.Status.Conditions {
+		if cond.Type == appsv1.DeploymentProgressing && cond.Reason == "ProgressDeadlineExceeded" {
+			return fmt.Sprintf("deployment '%s' exceeded its progress deadline", name), nil
+		}
+	}
+
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+
+	switch {
+	case deploy.Status.UpdatedReplicas < replicas:
+		return fmt.Sprintf("waiting for deployment '%s' rollout: %d of %d updated replicas", name,
+			deploy.Status.UpdatedReplicas, replicas), nil
+	case deploy.Status.Replicas > deploy.Status.UpdatedReplicas:
+		return fmt.Sprintf("waiting for deployment '%s' rollout: %d old replicas pending termination", name,
+			deploy.Status.Replicas-deploy.Status.UpdatedReplicas), nil
+	case deploy.Status.AvailableReplicas < deploy.Status.UpdatedReplicas:
+		return fmt.Sprintf("waiting for deployment '%s' rollout: %d of %d updated replicas available", name,
+			deploy.Status.AvailableReplicas, deploy.Status.UpdatedReplicas), nil
+	}
+
+	return "", nil
+}
+
+// getReadinessInterval will return the time to wait before checking whether the given ApacheBench is ready to start
+// the benchmark again.
+func getReadinessInterval(cr *v1a1.ApacheBench) time.Duration {
+	if cr.Spec.Readiness == nil || cr.Spec.Readiness.HTTPGet == nil {
+		return targetRetryInterval
+	}
+
+	if interval := cr.Spec.Readiness.HTTPGet.Interval; interval != nil && interval.Duration > 0 {
+		return interval.Duration
+	}
+	return defaultProbeInterval
+}
+
+// getProbeURLs will return the URLs that the HTTP probe is sent to for the given run of the given ApacheBench. The
+// probe is sent to the URL of the probe when set, otherwise to each URL that is benchmarked by the run.
+func getProbeURLs(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) []string {
+	if len(cr.Spec.Readiness.HTTPGet.URL) > 0 {
+		return []string{cr.Spec.Readiness.HTTPGet.URL}
+	}
+
+	if len(cr.Spec.Endpoints) <= 0 {
+		return []string{run.URL}
+	}
+
+	urls := make([]string, 0)
+	for _, endpoint := range cr.Spec.Endpoints {
+		if url := getEndpointURL(endpoint, run.URL); !containsString(urls, url) {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// getReadinessNotReadyReason will return the reason that the Deployment readiness check for the given ApacheBench has
+// not passed, or an empty string if it has passed. The HTTP probe is run by the benchmark Pod instead.
+func (r *ReconcileApacheBench) getReadinessNotReadyReason(cr *v1a1.ApacheBench) (string, error) {
+	readiness := cr.Spec.Readiness
+	if readiness == nil {
+		return "", nil
+	}
+
+	if len(readiness.Deployment) > 0 {
+		return r.getDeploymentNotReadyReason(cr.Namespace, readiness.Deployment)
+	}
+
+	return "", nil
+}
+
+// getReadinessScript will return the shell script that runs the given HTTP probe in the readiness container. Each URL
+// is passed as a positional parameter, and ab is used to send the probe so that it is sent from the benchmark
+// namespace in the same way as the benchmark, without verifying the certificate or following redirects. The script
+// exits once the probe of every URL has succeeded, the readiness timeout is enforced by the controller.
+func getReadinessScript(probe *v1a1.ApacheBenchHTTPProbeSpec) string {
+	interval := defaultProbeInterval
+	if probe.Interval != nil && probe.Interval.Duration > 0 {
+		interval = probe.Interval.Duration
+	}
+
+	timeout := defaultProbeTimeout
+	if probe.Timeout != nil && probe.Timeout.Duration > 0 {
+		timeout = probe.Timeout.Duration
+	}
+
+	check := `[ -n "$status" ] && [ "$status" -ge 200 ] && [ "$status" -lt 400 ]`
+	if probe.ExpectedStatus > 0 {
+		check = fmt.Sprintf(`[ "$status" = "%d" ]`, probe.ExpectedStatus)
+	}
+
+	lines := []string{
+		"while true; do",
+		"  ready=1",
+		`  for url in "$@"; do`,
+		fmt.Sprintf(`    status=$(ab -n 1 -v 2 -s %d "$url" 2>&1 | sed -n 's|^HTTP/[0-9.]* \([0-9][0-9]*\).*|\1|p' | head -n 1)`,
+			getSeconds(timeout)),
+		"    if " + check + "; then continue; fi",
+		`    echo "waiting for probe of $url to succeed: status ${status:-none}"`,
+		"    ready=0",
+		"    break",
+		"  done",
+		`  if [ "$ready" = "1" ]; then exit 0; fi`,
+		"  sleep " + strconv.Itoa(getSeconds(interval)),
+		"done",
+	}
+	return strings.Join(lines, "\n")
+}
+
+// getSeconds will return the given duration as a whole number of seconds, rounded up to at least one second.
+func getSeconds(d time.Duration) int {
+	return int(math.Max(1, math.Ceil(d.Seconds())))
+}
+
+// getReadinessTimeout will return the maximum time to wait for the given ApacheBench to be ready to start the
+// benchmark. Zero is returned if there is no limit.
+func getReadinessTimeout(cr *v1a1.ApacheBench) time.Duration {
+	if cr.Spec.Readiness == nil {
+		return 0 // Wait for the Target without a limit
+	}
+
+	if timeout := cr.Spec.Readiness.Timeout; timeout != nil && timeout.Duration > 0 {
+		return timeout.Duration
+	}
+	return defaultReadinessTimeout
+}
+
+// failReadiness will mark the given run of the given ApacheBench as failed, because the readiness checks did not pass
+// within the given timeout for the given reason.
+func failReadiness(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, timeout time.Duration, reason string) {
+	now := metav1.Now()
+	run.Phase = v1a1.ApacheBenchPhaseFailed
+	run.CompletionTime = &now
+	cr.Status.Phase = run.Phase
+
+	msg := fmt.Sprintf("not ready to start run %d after %s: %s", run.Number, timeout, reason)
+	log.Info("readiness timeout exceeded", "namespace", cr.Namespace, "name", cr.Name, "run", run.Number)
+	cr.Status.AddError(msg)
+	setCondition(cr, v1a1.ApacheBenchConditionJobCreated, corev1.ConditionFalse, readinessTimeoutReason, msg)
+	setCondition(cr, v1a1.ApacheBenchConditionRunning, corev1.ConditionFalse, readinessTimeoutReason, msg)
+	setCondition(cr, v1a1.ApacheBenchConditionSucceeded, corev1.ConditionFalse, readinessTimeoutReason, msg)
+	setCondition(cr, v1a1.ApacheBenchConditionFailed, corev1.ConditionTrue, readinessTimeoutReason, msg)
+}
+
+// failReadinessProbe will mark the given run of the given ApacheBench as failed, because the HTTP probe in the given
+// Pod did not succeed within the readiness timeout. The given Job is deleted, so that the benchmark does not start.
+func (r *ReconcileApacheBench) failReadinessProbe(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, job *batchv1.Job, pod corev1.Pod) error {
+	err := r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	failReadiness(cr, run, getReadinessTimeout(cr),
+		fmt.Sprintf("the readiness probe in pod '%s' did not succeed, see the logs of the '%s' container", pod.Name,
+			readinessContainerName))
+	return nil
+}
+
+// isProbingReadiness will return true if the readiness container in the given Pod has not yet completed.
+func isProbingReadiness(pod corev1.Pod) bool {
+	for _, cs := range pod.Status.InitContainerStatuses {
+		if cs.Name == readinessContainerName {
+			return cs.State.Terminated == nil || cs.State.Terminated.ExitCode != 0
+		}
+	}
+	return false
+}
+
+// isReadinessTimeoutExceeded will return true if the readiness checks for the given run of the given ApacheBench have
+// not passed within the readiness timeout, which starts when the run starts.
+func isReadinessTimeoutExceeded(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) bool {
+	timeout := getReadinessTimeout(cr)
+	return timeout > 0 && run.StartTime != nil && time.Since(run.StartTime.Time) >= timeout
+}
+
+// isWaitingForProbe will return true if the current run of the given ApacheBench is waiting for the HTTP probe in
+// the benchmark Pod to succeed.
+func isWaitingForProbe(cr *v1a1.ApacheBench) bool {
+	run := cr.GetCurrentRun()
+	if run == nil || run.Phase != v1a1.ApacheBenchPhasePending {
+		return false
+	}
+
+	cond := getCondition(cr, v1a1.ApacheBenchConditionRunning)
+	return cond != nil && cond.Status == corev1.ConditionFalse && cond.Reason == readinessProbeReason
+}
+
+// newReadinessContainer returns a new Container that runs the HTTP probe for the given run of the given ApacheBench.
+// The container runs as the first init container, so that neither the warm-up nor the benchmark start until the
+// probe has succeeded.
+func newReadinessContainer(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) corev1.Container {
+	cmd := []string{"/bin/sh", "-c", getReadinessScript(cr.Spec.Readiness.HTTPGet), readinessContainerName}
+	return corev1.Container{
+		Command:         append(cmd, getProbeURLs(cr, run)...),
+		Image:           getImage(cr),
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Name:            readinessContainerName,
+	}
+}
+
+// waitForReadiness will record that the given run of the given ApacheBench is waiting for the given reason before
+// the Job is created. The run fails once the readiness timeout has been exceeded.
+func waitForReadiness(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, reason string) {
+	if !isReadinessTimeoutExceeded(cr, run) {
+		log.Info("waiting for readiness", "namespace", cr.Namespace, "name", cr.Name, "reason", reason)
+		setCondition(cr, v1a1.ApacheBenchConditionJobCreated, corev1.ConditionFalse, targetNotReadyReason, reason)
+		return
+	}
+	failReadiness(cr, run, getReadinessTimeout(cr), reason)
+}
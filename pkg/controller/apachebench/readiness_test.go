@@ -0,0 +1,114 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetProbeURLs(t *testing.T) {
+	tests := []struct {
+		name      string
+		probeURL  string
+		runURL    string
+		endpoints []v1a1.ApacheBenchEndpointSpec
+		want      []string
+	}{
+		{
+			name:   "url to benchmark",
+			runURL: "http://app:8080/",
+			want:   []string{"http://app:8080/"},
+		},
+		{
+			name:     "probe url",
+			probeURL: "http://app:8080/healthz",
+			runURL:   "http://app:8080/",
+			endpoints: []v1a1.ApacheBenchEndpointSpec{
+				{Name: "home", URL: "/"},
+			},
+			want: []string{"http://app:8080/healthz"},
+		},
+		{
+			name: "endpoints only",
+			endpoints: []v1a1.ApacheBenchEndpointSpec{
+				{Name: "home", URL: "http://app:8080/"},
+				{Name: "api", URL: "http://api:8080/v1"},
+				{Name: "again", URL: "http://app:8080/"},
+			},
+			want: []string{"http://app:8080/", "http://api:8080/v1"},
+		},
+		{
+			name:   "endpoint paths",
+			runURL: "http://app:8080/",
+			endpoints: []v1a1.ApacheBenchEndpointSpec{
+				{Name: "home", URL: "/"},
+				{Name: "search", URL: "/search"},
+			},
+			want: []string{"http://app:8080/", "http://app:8080/search"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1a1.ApacheBench{}
+			cr.Spec.Endpoints = tt.endpoints
+			cr.Spec.Readiness = &v1a1.ApacheBenchReadinessSpec{
+				HTTPGet: &v1a1.ApacheBenchHTTPProbeSpec{URL: tt.probeURL},
+			}
+
+			got := getProbeURLs(cr, &v1a1.ApacheBenchRun{URL: tt.runURL})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getProbeURLs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPodSpecReadiness(t *testing.T) {
+	cr := &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"},
+		Spec:       v1a1.ApacheBenchSpec{URL: "http://example.com/"},
+	}
+	cr.Spec.Readiness = &v1a1.ApacheBenchReadinessSpec{
+		HTTPGet: &v1a1.ApacheBenchHTTPProbeSpec{ExpectedStatus: 204},
+	}
+	cr.Spec.Warmup = &v1a1.ApacheBenchWarmupSpec{Requests: 10}
+	run := &v1a1.ApacheBenchRun{Number: 1, URL: cr.Spec.URL}
+
+	r := newTestReconciler(t)
+	pod, err := r.newPodSpec(cr, run)
+	if err != nil {
+		t.Fatalf("newPodSpec() returned an error: %v", err)
+	}
+
+	if len(pod.InitContainers) != 2 || pod.InitContainers[0].Name != readinessContainerName ||
+		pod.InitContainers[1].Name != warmupContainerName {
+		t.Fatalf("newPodSpec() init containers = %+v, want readiness then warmup", pod.InitContainers)
+	}
+
+	cmd := pod.InitContainers[0].Command
+	if got := cmd[len(cmd)-1]; got != cr.Spec.URL {
+		t.Errorf("readiness container probes %q, want %q", got, cr.Spec.URL)
+	}
+	if script := cmd[2]; !strings.Contains(script, `[ "$status" = "204" ]`) {
+		t.Errorf("readiness script does not check the expected status:\n%s", script)
+	}
+}
@@ -15,13 +15,18 @@
 package apachebench
 
 import (
+	"context"
+
 	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
@@ -35,12 +40,17 @@ func (r *ReconcileApacheBench) reconcileResources(cr *v1a1.ApacheBench) error {
 }
 
 // watchResources will register Watches for each of the supported Resources.
-func watchResources(c controller.Controller) error {
+func watchResources(c controller.Controller, cl client.Client) error {
 	// Watch for changes to primary resource ApacheBench
 	if err := c.Watch(&source.Kind{Type: &v1a1.ApacheBench{}}, &handler.EnqueueRequestForObject{}); err != nil {
 		return err
 	}
 
+	// Watch for changes to ApacheBench instances used as the baseline by other instances.
+	if err := watchBaselineResource(c, cl); err != nil {
+		return err
+	}
+
 	// Watch for changes to Secret sub-resources owned by ApacheBench instances.
 	if err := watchOwnedResource(c, &corev1.Secret{}); err != nil {
 		return err
@@ -51,9 +61,102 @@ func watchResources(c controller.Controller) error {
 		return err
 	}
 
+	// Watch for changes to Pods created by the Jobs, which are not owned by ApacheBench instances directly.
+	if err := watchLabeledResource(c, &corev1.Pod{}); err != nil {
+		return err
+	}
+
+	// Watch for changes to Secrets and ConfigMaps referenced by ApacheBench instances, which are not owned by them.
+	if err := watchReferencedResource(c, cl, &corev1.Secret{}); err != nil {
+		return err
+	}
+
+	if err := watchReferencedResource(c, cl, &corev1.ConfigMap{}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// watchLabeledResource will register a Watch for the given resource labeled with the name of an ApacheBench instance.
+func watchLabeledResource(c controller.Controller, obj runtime.Object) error {
+	return c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			name, ok := a.Meta.GetLabels()[apacheBenchLabel]
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: a.Meta.GetNamespace(), Name: name}}}
+		}),
+	})
+}
+
+// mapBaselineResource will return a request for each other ApacheBench instance in the namespace of the given
+// ApacheBench that uses it as the baseline, so that the comparison is updated once a run of the baseline completes.
+func mapBaselineResource(cl client.Client, a handler.MapObject) []reconcile.Request {
+	list := &v1a1.ApacheBenchList{}
+	if err := cl.List(context.TODO(), list, client.InNamespace(a.Meta.GetNamespace())); err != nil {
+		log.Error(err, "unable to list apachebenches", "namespace", a.Meta.GetNamespace())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0)
+	for i := range list.Items {
+		cr := &list.Items[i]
+		if cr.Spec.Baseline != nil && cr.Spec.Baseline.Name == a.Meta.GetName() && cr.Name != a.Meta.GetName() {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}})
+		}
+	}
+	return requests
+}
+
+// mapReferencedResource will return a request for each ApacheBench instance in the namespace of the given Secret or
+// ConfigMap that references it in the headers or cookies and starts a new run when the referenced data changes, or
+// that needs it and whose Job could not be created, so that the Job is created once the resource is fixed. The objects
+// created by the operator, eg. the results ConfigMaps, are labeled with the name of an ApacheBench instance and are
+// ignored without listing the instances.
+func mapReferencedResource(cl client.Client, a handler.MapObject) []reconcile.Request {
+	if _, ok := a.Meta.GetLabels()[apacheBenchLabel]; ok {
+		return nil
+	}
+
+	list := &v1a1.ApacheBenchList{}
+	if err := cl.List(context.TODO(), list, client.InNamespace(a.Meta.GetNamespace())); err != nil {
+		log.Error(err, "unable to list apachebenches", "namespace", a.Meta.GetNamespace())
+		return nil
+	}
+
+	requests := make([]reconcile.Request, 0)
+	for i := range list.Items {
+		cr := &list.Items[i]
+		name := a.Meta.GetName()
+		if (cr.Spec.RerunOnReferenceChange && isReferenced(cr, a.Object, name)) ||
+			(isJobCreationFailed(cr) && isRequired(cr, a.Object, name)) {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cr.Namespace, Name: cr.Name}})
+		}
+	}
+	return requests
+}
+
+// watchBaselineResource will register a Watch for the ApacheBench instances used as the baseline by other instances.
+func watchBaselineResource(c controller.Controller, cl client.Client) error {
+	return c.Watch(&source.Kind{Type: &v1a1.ApacheBench{}}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			return mapBaselineResource(cl, a)
+		}),
+	})
+}
+
+// watchReferencedResource will register a Watch for the given resource referenced by or needed by an ApacheBench
+// instance, that is not owned by the instance.
+func watchReferencedResource(c controller.Controller, cl client.Client, obj runtime.Object) error {
+	return c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestsFromMapFunc{
+		ToRequests: handler.ToRequestsFunc(func(a handler.MapObject) []reconcile.Request {
+			return mapReferencedResource(cl, a)
+		}),
+	})
+}
+
 // watchOwnedResource will register a Watch for the given resource owned by an ApacheBench instance.
 func watchOwnedResource(c controller.Controller, obj runtime.Object) error {
 	return c.Watch(&source.Kind{Type: obj}, &handler.EnqueueRequestForOwner{
@@ -0,0 +1,101 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"context"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+// countingClient is a client that counts the number of List calls.
+type countingClient struct {
+	client.Client
+	lists int
+}
+
+// List will count the call before listing the objects using the wrapped client.
+func (c *countingClient) List(ctx context.Context, list runtime.Object, opts ...client.ListOption) error {
+	c.lists++
+	return c.Client.List(ctx, list, opts...)
+}
+
+func TestMapReferencedResource(t *testing.T) {
+	cr := &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"},
+		Spec: v1a1.ApacheBenchSpec{
+			HeadersFrom: []v1a1.ApacheBenchValueFromSource{{
+				ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "headers"},
+					Key:                  "accept",
+				},
+				Name: "Accept",
+			}},
+			RerunOnReferenceChange: true,
+			URL:                    "http://example.com/",
+		},
+	}
+	cl := &countingClient{Client: newTestReconciler(t, cr).client}
+
+	tests := []struct {
+		name      string
+		configMap *corev1.ConfigMap
+		want      int
+		wantLists int
+	}{
+		{
+			name:      "referenced",
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "headers", Namespace: "benchmark"}},
+			want:      1,
+			wantLists: 1,
+		},
+		{
+			name:      "not referenced",
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "benchmark"}},
+			want:      0,
+			wantLists: 1,
+		},
+		{
+			name: "results",
+			configMap: &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:      "example-1-abcde-results-0",
+				Namespace: "benchmark",
+				Labels:    map[string]string{apacheBenchLabel: "example", runLabel: "1"},
+			}},
+			want:      0,
+			wantLists: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cl.lists = 0
+			requests := mapReferencedResource(cl, handler.MapObject{Meta: tt.configMap, Object: tt.configMap})
+			if len(requests) != tt.want {
+				t.Errorf("mapReferencedResource() = %v, want %d request(s)", requests, tt.want)
+			}
+			if cl.lists != tt.wantLists {
+				t.Errorf("mapReferencedResource() listed %d time(s), want %d", cl.lists, tt.wantLists)
+			}
+		})
+	}
+}
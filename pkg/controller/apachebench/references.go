@@ -0,0 +1,168 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+	"strconv"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// getReferencedData will return the data for each Secret and ConfigMap key that is referenced by the given
+// ApacheBench, in order, when a new run is enabled for changes to the referenced data. A missing Secret, ConfigMap or
+// key is returned as nil, so that a new run is started once it is created.
+func (r *ReconcileApacheBench) getReferencedData(cr *v1a1.ApacheBench) ([][]byte, error) {
+	if !cr.Spec.RerunOnReferenceChange {
+		return nil, nil
+	}
+
+	data := make([][]byte, 0)
+	for _, src := range getValueSources(cr) {
+		var value []byte
+
+		if src.SecretKeyRef != nil {
+			secret := &corev1.Secret{}
+			if err := r.fetchObject(cr.Namespace, src.SecretKeyRef.Name, secret); err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			value = secret.Data[src.SecretKeyRef.Key]
+		} else if src.ConfigMapKeyRef != nil {
+			cm := &corev1.ConfigMap{}
+			if err := r.fetchObject(cr.Namespace, src.ConfigMapKeyRef.Name, cm); err != nil && !apierrors.IsNotFound(err) {
+				return nil, err
+			}
+			if v, ok := cm.Data[src.ConfigMapKeyRef.Key]; ok {
+				value = []byte(v)
+			} else {
+				value = cm.BinaryData[src.ConfigMapKeyRef.Key]
+			}
+		}
+
+		data = append(data, value)
+	}
+
+	return data, nil
+}
+
+// getReferenceEnv will return the environment variables for the benchmark container that contain the headers and
+// cookies from Secret and ConfigMap keys for the given ApacheBench. The name of each header or cookie is set directly,
+// while the value references the key, so that the value does not appear in the Pod spec.
+func getReferenceEnv(cr *v1a1.ApacheBench) []corev1.EnvVar {
+	env := make([]corev1.EnvVar, 0)
+
+	for i, src := range cr.Spec.HeadersFrom {
+		env = append(env, newValueFromEnvVars(src, headerNameEnvPrefix, headerValueEnvPrefix, i)...)
+	}
+
+	for i, src := range cr.Spec.CookiesFrom {
+		env = append(env, newValueFromEnvVars(src, cookieNameEnvPrefix, cookieValueEnvPrefix, i)...)
+	}
+
+	return env
+}
+
+// getValueSources will return each of the headers and cookies from Secret and ConfigMap keys for the given
+// ApacheBench.
+func getValueSources(cr *v1a1.ApacheBench) []v1a1.ApacheBenchValueFromSource {
+	srcs := make([]v1a1.ApacheBenchValueFromSource, 0)
+	srcs = append(srcs, cr.Spec.HeadersFrom...)
+	return append(srcs, cr.Spec.CookiesFrom...)
+}
+
+// isReferenced will return true if the given Secret or ConfigMap is referenced by a header or cookie for the given
+// ApacheBench.
+func isReferenced(cr *v1a1.ApacheBench, obj runtime.Object, name string) bool {
+	for _, src := range getValueSources(cr) {
+		switch obj.(type) {
+		case *corev1.Secret:
+			if src.SecretKeyRef != nil && src.SecretKeyRef.Name == name {
+				return true
+			}
+		case *corev1.ConfigMap:
+			if src.ConfigMapKeyRef != nil && src.ConfigMapKeyRef.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRequired will return true if the given Secret or ConfigMap with the given name is needed to create the Job for
+// the given ApacheBench, for the credentials or client certificate, the POST or PUT data, or the headers and cookies.
+func isRequired(cr *v1a1.ApacheBench, obj runtime.Object, name string) bool {
+	switch obj.(type) {
+	case *corev1.Secret:
+		if cr.Spec.SecretName == name {
+			return true
+		}
+	case *corev1.ConfigMap:
+		if cr.Spec.ConfigMapName == name {
+			return true
+		}
+	}
+	return isReferenced(cr, obj, name)
+}
+
+// newValueFromEnvVars returns the environment variables for the name and value of the given header or cookie, using
+// the given prefixes and index.
+func newValueFromEnvVars(src v1a1.ApacheBenchValueFromSource, namePrefix string, valuePrefix string, index int) []corev1.EnvVar {
+	return []corev1.EnvVar{{
+		Name:  namePrefix + strconv.Itoa(index),
+		Value: src.Name,
+	}, {
+		Name: valuePrefix + strconv.Itoa(index),
+		ValueFrom: &corev1.EnvVarSource{
+			ConfigMapKeyRef: src.ConfigMapKeyRef,
+			SecretKeyRef:    src.SecretKeyRef,
+		},
+	}}
+}
+
+// validateReferences will check that each header and cookie from Secret and ConfigMap keys for the given ApacheBench
+// has a name and references exactly one key. If any are invalid, the ApacheBench is marked as failed and an error is
+// returned.
+func (r *ReconcileApacheBench) validateReferences(cr *v1a1.ApacheBench) error {
+	var failed = false
+
+	for _, src := range getValueSources(cr) {
+		var msg string
+		switch {
+		case len(src.Name) <= 0:
+			msg = "a name must be set for each header or cookie from a secret or configmap"
+		case (src.SecretKeyRef == nil) == (src.ConfigMapKeyRef == nil):
+			msg = fmt.Sprintf("exactly one of secretKeyRef or configMapKeyRef must be set for '%s'", src.Name)
+		case src.SecretKeyRef != nil && (len(src.SecretKeyRef.Name) <= 0 || len(src.SecretKeyRef.Key) <= 0):
+			msg = fmt.Sprintf("a secret name and key must be set for '%s'", src.Name)
+		case src.ConfigMapKeyRef != nil && (len(src.ConfigMapKeyRef.Name) <= 0 || len(src.ConfigMapKeyRef.Key) <= 0):
+			msg = fmt.Sprintf("a configmap name and key must be set for '%s'", src.Name)
+		default:
+			continue
+		}
+
+		failed = true
+		cr.Status.AddError(msg)
+	}
+
+	if failed {
+		return r.failJobCreation(cr, "InvalidReference", "invalid header or cookie reference")
+	}
+
+	return nil
+}
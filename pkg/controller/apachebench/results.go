@@ -0,0 +1,304 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+const (
+	// connectionTimesHeader is the header line for the connection times table in the ab report.
+	connectionTimesHeader = "Connection Times (ms)"
+
+	// csvSection is the name of the section of the Pod logs that contains the CSV percentile table.
+	csvSection = "csv"
+
+	// gnuplotSection is the name of the section of the Pod logs that contains the gnuplot data.
+	gnuplotSection = "gnuplot"
+
+	// maxFileSectionSize is the maximum size in bytes of the gnuplot or CSV data printed to the Pod logs. Together with
+	// the ab output, this stays below the size at which the kubelet rotates the container logs, 10MiB by default.
+	maxFileSectionSize = 4 * 1024 * 1024
+
+	// maxPodLogsSize is the maximum number of bytes read from the logs of a benchmark Pod.
+	maxPodLogsSize = 8 * 1024 * 1024
+
+	// outputSectionPrefix and outputSectionSuffix surround the name of a section on the line in the Pod logs that
+	// marks the start of the section. Sections are printed after the ab output by the benchmark container script.
+	outputSectionPrefix = "==> apachebench:"
+	outputSectionSuffix = " <=="
+
+	// percentilesHeader is the header line for the percentage served table in the ab report.
+	percentilesHeader = "Percentage of the requests served within a certain time (ms)"
+
+	// skippedSectionSuffix is added to the name of the section for the gnuplot or CSV data in the Pod logs when the
+	// data is larger than maxFileSectionSize. The section contains the size of the data instead.
+	skippedSectionSuffix = "-skipped"
+)
+
+// getPercentiles will return the percentiles for the given request times, calculated in the same way as the
+// percentage served table in the ab report.
+func getPercentiles(times []float64) *v1a1.ApacheBenchPercentiles {
+	if len(times) <= 0 {
+		return nil
+	}
+
+	sorted := append([]float64{}, times...)
+	sort.Float64s(sorted)
+
+	p := &v1a1.ApacheBenchPercentiles{}
+	for _, percent := range []int{50, 66, 75, 80, 90, 95, 98, 99} {
+		parsePercentile(p, fmt.Sprintf("%d%%", percent), sorted[len(sorted)*percent/100])
+	}
+	p.P100 = sorted[len(sorted)-1]
+
+	return p
+}
+
+// parseConnectionTimes will parse the given fields from a row of the connection times table.
+// The row contains either min, mean, [+/-sd], median and max values or, when the median is disabled, min, avg and
+// max values.
+func parseConnectionTimes(fields []string) (v1a1.ApacheBenchConnectionTimes, error) {
+	ct := v1a1.ApacheBenchConnectionTimes{}
+
+	values := make([]float64, 0, len(fields))
+	for _, f := range fields {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return ct, err
+		}
+		values = append(values, v)
+	}
+
+	switch len(values) {
+	case 5:
+		ct.Min, ct.Mean, ct.StdDev, ct.Median, ct.Max = values[0], values[1], values[2], values[3], values[4]
+	case 3:
+		ct.Min, ct.Mean, ct.Max = values[0], values[1], values[2]
+	default:
+		return ct, errors.New("unexpected number of connection time values")
+	}
+
+	return ct, nil
+}
+
+// parseFloat will parse the first field of the given value as a float, ignoring any trailing units.
+func parseFloat(value string) float64 {
+	fields := strings.Fields(value)
+	if len(fields) <= 0 {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(fields[0], 64)
+	return v
+}
+
+// parseInt will parse the first field of the given value as an integer, ignoring any trailing units.
+func parseInt(value string) int64 {
+	fields := strings.Fields(value)
+	if len(fields) <= 0 {
+		return 0
+	}
+	v, _ := strconv.ParseInt(fields[0], 10, 64)
+	return v
+}
+
+// parseGnuplot will parse the total time for each request from the given gnuplot data.
+func parseGnuplot(data string) ([]float64, error) {
+	times := make([]float64, 0)
+
+	for i, line := range strings.Split(data, "\n") {
+		fields := strings.Split(line, "\t")
+		if i == 0 || len(strings.TrimSpace(line)) <= 0 {
+			continue // Skip the header and blank lines
+		}
+		if len(fields) < 6 {
+			return nil, fmt.Errorf("unexpected number of gnuplot values on line %d", i+1)
+		}
+
+		v, err := strconv.ParseFloat(strings.TrimSpace(fields[4]), 64)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, v)
+	}
+
+	return times, nil
+}
+
+// parsePercentile will set the value for the given percentage on the given percentiles.
+func parsePercentile(p *v1a1.ApacheBenchPercentiles, percent string, value float64) {
+	switch percent {
+	case "50%":
+		p.P50 = value
+	case "66%":
+		p.P66 = value
+	case "75%":
+		p.P75 = value
+	case "80%":
+		p.P80 = value
+	case "90%":
+		p.P90 = value
+	case "95%":
+		p.P95 = value
+	case "98%":
+		p.P98 = value
+	case "99%":
+		p.P99 = value
+	case "100%":
+		p.P100 = value
+	}
+}
+
+// parseResults will parse the report printed by ab into an ApacheBenchSummary.
+// An error is returned if the output does not contain an ab report.
+func parseResults(output string) (*v1a1.ApacheBenchSummary, error) {
+	summary := &v1a1.ApacheBenchSummary{}
+	section := ""
+	found := false
+	timePerRequest := 0
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) <= 0 {
+			continue
+		}
+
+		switch line {
+		case connectionTimesHeader:
+			section = line
+			summary.ConnectionTimes = &v1a1.ApacheBenchConnectionTimesSummary{}
+			continue
+		case percentilesHeader:
+			section = line
+			summary.Percentiles = &v1a1.ApacheBenchPercentiles{}
+			continue
+		}
+
+		if section == percentilesHeader {
+			fields := strings.Fields(line)
+			if len(fields) >= 2 && strings.HasSuffix(fields[0], "%") {
+				parsePercentile(summary.Percentiles, fields[0], parseFloat(fields[1]))
+			}
+			continue
+		}
+
+		idx := strings.Index(line, ":")
+		if idx <= 0 {
+			continue
+		}
+		key := line[:idx]
+		value := strings.TrimSpace(line[idx+1:])
+
+		if section == connectionTimesHeader {
+			ct, err := parseConnectionTimes(strings.Fields(value))
+			if err != nil {
+				continue // Skip the header row and any warnings
+			}
+
+			switch key {
+			case "Connect":
+				summary.ConnectionTimes.Connect = ct
+			case "Processing":
+				summary.ConnectionTimes.Processing = ct
+			case "Waiting":
+				summary.ConnectionTimes.Waiting = ct
+			case "Total":
+				summary.ConnectionTimes.Total = ct
+			}
+			continue
+		}
+
+		switch key {
+		case "Server Software":
+			summary.ServerSoftware = value
+		case "Server Hostname":
+			summary.ServerHostname = value
+		case "Server Port":
+			summary.ServerPort = int32(parseInt(value))
+		case "Document Path":
+			summary.DocumentPath = value
+		case "Document Length":
+			summary.DocumentLength = parseInt(value)
+		case "Concurrency Level":
+			summary.ConcurrencyLevel = int32(parseInt(value))
+		case "Time taken for tests":
+			summary.TimeTaken = parseFloat(value)
+		case "Complete requests":
+			summary.CompleteRequests = parseInt(value)
+			found = true
+		case "Failed requests":
+			summary.FailedRequests = parseInt(value)
+		case "Write errors":
+			summary.WriteErrors = parseInt(value)
+		case "Non-2xx responses":
+			summary.Non2xxResponses = parseInt(value)
+		case "Keep-Alive requests":
+			summary.KeepAliveRequests = parseInt(value)
+		case "Total transferred":
+			summary.TotalTransferred = parseInt(value)
+		case "HTML transferred":
+			summary.HTMLTransferred = parseInt(value)
+		case "Requests per second":
+			summary.RequestsPerSecond = parseFloat(value)
+		case "Time per request":
+			// The first value is the mean, the second value is the mean across all concurrent requests.
+			if timePerRequest == 0 {
+				summary.TimePerRequest = parseFloat(value)
+			} else {
+				summary.TimePerRequestAcrossConcurrency = parseFloat(value)
+			}
+			timePerRequest++
+		case "Transfer rate":
+			summary.TransferRate = parseFloat(value)
+		}
+	}
+
+	if !found {
+		return nil, errors.New("unable to locate ab report in output")
+	}
+
+	return summary, nil
+}
+
+// splitOutputSections will split the given Pod logs into the ab output and any sections that follow it.
+// The sections are returned by name.
+func splitOutputSections(logs string) (string, map[string]string) {
+	output := &strings.Builder{}
+	builders := make(map[string]*strings.Builder)
+
+	current := output
+	for _, line := range strings.SplitAfter(logs, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, outputSectionPrefix) && strings.HasSuffix(trimmed, outputSectionSuffix) {
+			name := strings.TrimSuffix(strings.TrimPrefix(trimmed, outputSectionPrefix), outputSectionSuffix)
+			current = &strings.Builder{}
+			builders[name] = current
+			continue
+		}
+		current.WriteString(line)
+	}
+
+	sections := make(map[string]string)
+	for name, b := range builders {
+		sections[name] = b.String()
+	}
+	return output.String(), sections
+}
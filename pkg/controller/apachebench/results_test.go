@@ -0,0 +1,347 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+const (
+	// abHeader is the banner printed by ab before each report.
+	abHeader = `This is ApacheBench, Version 2.3 <$Revision: 1874286 $>
+Copyright 1996 Adam Twiss, Zeus Technology Ltd, http://www.zeustech.net/
+Licensed to The Apache Software Foundation, http://www.apache.org/
+
+Benchmarking app.benchmark.svc (be patient)
+Completed 100 requests
+Completed 200 requests
+Finished 200 requests
+
+
+Server Software:        Apache/2.4.43
+Server Hostname:        app.benchmark.svc
+Server Port:            8080
+
+Document Path:          /index.html
+Document Length:        45 bytes
+
+Concurrency Level:      10
+Time taken for tests:   0.412 seconds
+Complete requests:      200
+Failed requests:        3
+   (Connect: 0, Receive: 0, Length: 3, Exceptions: 0)
+Write errors:           1
+Non-2xx responses:      2
+Keep-Alive requests:    198
+Total transferred:      57600 bytes
+HTML transferred:       9000 bytes
+Requests per second:    485.44 [#/sec] (mean)
+Time per request:       20.600 [ms] (mean)
+Time per request:       2.060 [ms] (mean, across all concurrent requests)
+Transfer rate:          136.53 [Kbytes/sec] received
+`
+
+	// abConnectionTimes is the connection times table printed by ab by default, including a warning.
+	abConnectionTimes = `
+Connection Times (ms)
+              min  mean[+/-sd] median   max
+Connect:        0    1   2.1      0      12
+Processing:     4   19   6.3     18      55
+Waiting:        3   18   6.2     17      54
+Total:          4   20   6.8     19      58
+WARNING: The median and mean for the initial connection time are not within a normal deviation
+        These results are probably not that reliable.
+`
+
+	// abConnectionTimesNoConfidence is the connection times table printed by ab with the -S option.
+	abConnectionTimesNoConfidence = `
+Connection Times (ms)
+              min   avg   max
+Connect:        0    1   12
+Processing:     4   19   55
+Waiting:        3   18   54
+Total:          4   20   58
+`
+
+	// abPercentiles is the percentage served table printed by ab unless the -d option is set.
+	abPercentiles = `
+Percentage of the requests served within a certain time (ms)
+  50%     19
+  66%     21
+  75%     23
+  80%     24
+  90%     28
+  95%     33
+  98%     41
+  99%     47
+ 100%     58 (longest request)
+`
+
+	// abHTML is the report printed by ab with the -w option.
+	abHTML = `<p>
+ This is ApacheBench, Version 2.3 <i>&lt;$Revision: 1874286 $&gt;</i><br>
+ Copyright 1996 Adam Twiss, Zeus Technology Ltd, http://www.zeustech.net/<br>
+ Licensed to The Apache Software Foundation, http://www.apache.org/<br>
+</p>
+<p>
+..done
+<table >
+<tr ><th colspan=2 bgcolor=white>Server Software:</th><td colspan=2 bgcolor=white>Apache/2.4.43</td></tr>
+<tr ><th colspan=2 bgcolor=white>Server Hostname:</th><td colspan=2 bgcolor=white>app.benchmark.svc</td></tr>
+<tr ><th colspan=2 bgcolor=white>Server Port:</th><td colspan=2 bgcolor=white>8080</td></tr>
+<tr ><th colspan=2 bgcolor=white>Document Path:</th><td colspan=2 bgcolor=white>/index.html</td></tr>
+<tr ><th colspan=2 bgcolor=white>Document Length:</th><td colspan=2 bgcolor=white>45 bytes</td></tr>
+<tr ><th colspan=2 bgcolor=white>Concurrency Level:</th><td colspan=2 bgcolor=white>10</td></tr>
+<tr ><th colspan=2 bgcolor=white>Time taken for tests:</th><td colspan=2 bgcolor=white>  0.412 seconds</td></tr>
+<tr ><th colspan=2 bgcolor=white>Complete requests:</th><td colspan=2 bgcolor=white>200</td></tr>
+<tr ><th colspan=2 bgcolor=white>Failed requests:</th><td colspan=2 bgcolor=white>0</td></tr>
+<tr ><th colspan=2 bgcolor=white>Requests per second:</th><td colspan=2 bgcolor=white>485.44</td></tr>
+</table>
+`
+
+	// abGnuplot is the gnuplot section of the Pod logs when the gnuplot file is enabled.
+	abGnuplot = `==> apachebench:gnuplot <==
+starttime	seconds	ctime	dtime	ttime	wait
+Sat Oct 17 10:15:30 2026	1792232130	0	4	4	3
+Sat Oct 17 10:15:30 2026	1792232130	1	18	19	17
+Sat Oct 17 10:15:30 2026	1792232130	0	22	22	21
+Sat Oct 17 10:15:30 2026	1792232130	2	56	58	54
+`
+)
+
+// abSummary is the summary for the report in abHeader.
+var abSummary = v1a1.ApacheBenchSummary{
+	CompleteRequests:                200,
+	ConcurrencyLevel:                10,
+	DocumentLength:                  45,
+	DocumentPath:                    "/index.html",
+	FailedRequests:                  3,
+	HTMLTransferred:                 9000,
+	KeepAliveRequests:               198,
+	Non2xxResponses:                 2,
+	RequestsPerSecond:               485.44,
+	ServerHostname:                  "app.benchmark.svc",
+	ServerPort:                      8080,
+	ServerSoftware:                  "Apache/2.4.43",
+	TimePerRequest:                  20.6,
+	TimePerRequestAcrossConcurrency: 2.06,
+	TimeTaken:                       0.412,
+	TotalTransferred:                57600,
+	TransferRate:                    136.53,
+	WriteErrors:                     1,
+}
+
+// withTables will return a copy of abSummary with the given connection times and percentiles.
+func withTables(ct *v1a1.ApacheBenchConnectionTimesSummary, p *v1a1.ApacheBenchPercentiles) *v1a1.ApacheBenchSummary {
+	summary := abSummary
+	summary.ConnectionTimes = ct
+	summary.Percentiles = p
+	return &summary
+}
+
+// floatsEqual will return true if the given values are equal, allowing for rounding errors.
+func floatsEqual(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestParseResults(t *testing.T) {
+	connectionTimes := &v1a1.ApacheBenchConnectionTimesSummary{
+		Connect:    v1a1.ApacheBenchConnectionTimes{Min: 0, Mean: 1, StdDev: 2.1, Median: 0, Max: 12},
+		Processing: v1a1.ApacheBenchConnectionTimes{Min: 4, Mean: 19, StdDev: 6.3, Median: 18, Max: 55},
+		Waiting:    v1a1.ApacheBenchConnectionTimes{Min: 3, Mean: 18, StdDev: 6.2, Median: 17, Max: 54},
+		Total:      v1a1.ApacheBenchConnectionTimes{Min: 4, Mean: 20, StdDev: 6.8, Median: 19, Max: 58},
+	}
+	noConfidence := &v1a1.ApacheBenchConnectionTimesSummary{
+		Connect:    v1a1.ApacheBenchConnectionTimes{Min: 0, Mean: 1, Max: 12},
+		Processing: v1a1.ApacheBenchConnectionTimes{Min: 4, Mean: 19, Max: 55},
+		Waiting:    v1a1.ApacheBenchConnectionTimes{Min: 3, Mean: 18, Max: 54},
+		Total:      v1a1.ApacheBenchConnectionTimes{Min: 4, Mean: 20, Max: 58},
+	}
+	percentiles := &v1a1.ApacheBenchPercentiles{
+		P50: 19, P66: 21, P75: 23, P80: 24, P90: 28, P95: 33, P98: 41, P99: 47, P100: 58,
+	}
+
+	tests := []struct {
+		name    string
+		output  string
+		want    *v1a1.ApacheBenchSummary
+		wantErr bool
+	}{
+		{
+			name:   "default",
+			output: abHeader + abConnectionTimes + abPercentiles,
+			want:   withTables(connectionTimes, percentiles),
+		},
+		{
+			name:   "without confidence estimators (-S)",
+			output: abHeader + abConnectionTimesNoConfidence + abPercentiles,
+			want:   withTables(noConfidence, percentiles),
+		},
+		{
+			name:   "without percentiles (-d)",
+			output: abHeader + abConnectionTimes,
+			want:   withTables(connectionTimes, nil),
+		},
+		{
+			name:   "without confidence estimators or percentiles (-S -d)",
+			output: abHeader + abConnectionTimesNoConfidence,
+			want:   withTables(noConfidence, nil),
+		},
+		{
+			name:   "gnuplot section",
+			output: abHeader + abConnectionTimes + abPercentiles + abGnuplot,
+			want:   withTables(connectionTimes, percentiles),
+		},
+		{
+			name:    "html (-w)",
+			output:  abHTML,
+			wantErr: true,
+		},
+		{
+			name:    "no report",
+			output:  "apr_socket_recv: Connection refused (111)\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, _ := splitOutputSections(tt.output)
+
+			got, err := parseResults(output)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseResults() expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseResults() returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseResults() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGnuplot(t *testing.T) {
+	output, sections := splitOutputSections(abHeader + abConnectionTimes + abPercentiles + abGnuplot)
+	if output != abHeader+abConnectionTimes+abPercentiles {
+		t.Errorf("splitOutputSections() output includes the sections:\n%s", output)
+	}
+
+	times, err := parseGnuplot(sections[gnuplotSection])
+	if err != nil {
+		t.Fatalf("parseGnuplot() returned an error: %v", err)
+	}
+	if want := []float64{4, 19, 22, 58}; !reflect.DeepEqual(times, want) {
+		t.Fatalf("parseGnuplot() = %v, want %v", times, want)
+	}
+
+	want := &v1a1.ApacheBenchPercentiles{
+		P50: 22, P66: 22, P75: 58, P80: 58, P90: 58, P95: 58, P98: 58, P99: 58, P100: 58,
+	}
+	if got := getPercentiles(times); !reflect.DeepEqual(got, want) {
+		t.Errorf("getPercentiles() = %+v, want %+v", got, want)
+	}
+
+	if _, err := parseGnuplot("starttime\tseconds\tctime\tdtime\tttime\twait\nSat Oct 17 10:15:30 2026\t1\n"); err == nil {
+		t.Errorf("parseGnuplot() expected an error for a short line")
+	}
+}
+
+func TestAggregateSummaries(t *testing.T) {
+	first := v1a1.ApacheBenchSummary{
+		CompleteRequests:  300,
+		ConcurrencyLevel:  10,
+		FailedRequests:    1,
+		RequestsPerSecond: 100,
+		TimePerRequest:    20,
+		TimeTaken:         3,
+		TotalTransferred:  3000,
+		TransferRate:      10,
+		ConnectionTimes: &v1a1.ApacheBenchConnectionTimesSummary{
+			Total: v1a1.ApacheBenchConnectionTimes{Min: 5, Mean: 20, StdDev: 3, Median: 19, Max: 80},
+		},
+		Percentiles: &v1a1.ApacheBenchPercentiles{P50: 18, P99: 70, P100: 80},
+	}
+	second := v1a1.ApacheBenchSummary{
+		CompleteRequests:  100,
+		ConcurrencyLevel:  5,
+		FailedRequests:    2,
+		RequestsPerSecond: 50,
+		TimePerRequest:    40,
+		TimeTaken:         2,
+		TotalTransferred:  1000,
+		TransferRate:      5,
+		ConnectionTimes: &v1a1.ApacheBenchConnectionTimesSummary{
+			Total: v1a1.ApacheBenchConnectionTimes{Min: 8, Mean: 40, StdDev: 4, Median: 38, Max: 120},
+		},
+		Percentiles: &v1a1.ApacheBenchPercentiles{P50: 36, P99: 110, P100: 120},
+	}
+
+	if got := aggregateSummaries(nil); got != nil {
+		t.Errorf("aggregateSummaries(nil) = %+v, want nil", got)
+	}
+
+	// The first summary completed three times as many requests, so it has three times the weight.
+	agg := aggregateSummaries([]v1a1.ApacheBenchSummary{first, second})
+	checks := []struct {
+		name string
+		got  float64
+		want float64
+	}{
+		{"completeRequests", float64(agg.CompleteRequests), 400},
+		{"concurrencyLevel", float64(agg.ConcurrencyLevel), 15},
+		{"failedRequests", float64(agg.FailedRequests), 3},
+		{"requestsPerSecond", agg.RequestsPerSecond, 150},
+		{"timePerRequest", agg.TimePerRequest, 25},
+		{"timePerRequestAcrossConcurrency", agg.TimePerRequestAcrossConcurrency, 1000.0 / 150},
+		{"timeTaken", agg.TimeTaken, 3},
+		{"totalTransferred", float64(agg.TotalTransferred), 4000},
+		{"transferRate", agg.TransferRate, 15},
+		{"total.min", agg.ConnectionTimes.Total.Min, 5},
+		{"total.mean", agg.ConnectionTimes.Total.Mean, 25},
+		{"total.sd", agg.ConnectionTimes.Total.StdDev, math.Sqrt(0.75*(9+25) + 0.25*(16+225))},
+		{"total.median", agg.ConnectionTimes.Total.Median, 23.75},
+		{"total.max", agg.ConnectionTimes.Total.Max, 120},
+		{"p50", agg.Percentiles.P50, 22.5},
+		{"p99", agg.Percentiles.P99, 80},
+		{"p100", agg.Percentiles.P100, 120},
+	}
+	for _, c := range checks {
+		if !floatsEqual(c.got, c.want) {
+			t.Errorf("aggregate %s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	// The tables are only combined when every summary reported them.
+	second.ConnectionTimes, second.Percentiles = nil, nil
+	agg = aggregateSummaries([]v1a1.ApacheBenchSummary{first, second})
+	if agg.ConnectionTimes != nil || agg.Percentiles != nil {
+		t.Errorf("aggregateSummaries() combined partial tables: %+v %+v", agg.ConnectionTimes, agg.Percentiles)
+	}
+
+	// The summaries are weighted equally when no requests were completed.
+	first.CompleteRequests, second.CompleteRequests = 0, 0
+	if got := aggregateSummaries([]v1a1.ApacheBenchSummary{first, second}).TimePerRequest; !floatsEqual(got, 30) {
+		t.Errorf("aggregate timePerRequest without requests = %v, want 30", got)
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// defaultRunHistoryLimit is the number of runs to keep when a limit is not specified in the CR.
+	defaultRunHistoryLimit = 10
+
+	// runTriggerInitial is the trigger for the first run of an ApacheBench without a Schedule.
+	runTriggerInitial = "Initial"
+
+	// runTriggerManual is the trigger for a run started by changing the run ID annotation.
+	runTriggerManual = "Manual"
+
+	// runTriggerSchedule is the trigger for a run started by the Schedule.
+	runTriggerSchedule = "Schedule"
+
+	// runTriggerSpecChange is the trigger for a run started because the spec has changed since the previous run.
+	runTriggerSpecChange = "SpecChange"
+
+	// specHashAnnotation is the annotation on each Job that contains the hash of the spec used for the run.
+	specHashAnnotation = "httpd.apache.org/spec-hash"
+)
+
+// getNextScheduleTime will return the next time that the given ApacheBench is scheduled to run after the given time.
+// A zero time is returned if the ApacheBench does not have a valid Schedule.
+func getNextScheduleTime(cr *v1a1.ApacheBench, now time.Time) time.Time {
+	if len(cr.Spec.Schedule) <= 0 {
+		return time.Time{}
+	}
+
+	sched, err := parseSchedule(cr.Spec.Schedule)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return sched.next(now)
+}
+
+// getRunHistoryLimit will return the number of runs to keep for the given ApacheBench.
+func getRunHistoryLimit(cr *v1a1.ApacheBench) int {
+	if cr.Spec.RunHistoryLimit != nil && *cr.Spec.RunHistoryLimit > 0 {
+		return int(*cr.Spec.RunHistoryLimit)
+	}
+	return defaultRunHistoryLimit
+}
+
+// getRunJobName will return the name of the Job for the run with the given number.
+// The first run uses the name of the ApacheBench, so that any Job created before runs were tracked is adopted.
+func getRunJobName(cr *v1a1.ApacheBench, number int32) string {
+	if number <= 1 {
+		return cr.Name
+	}
+	return fmt.Sprintf("%s-%d", cr.Name, number)
+}
+
+// getRunJobs will return the names of each of the Jobs for the given run. A capacity search has a Job for each
+// iteration.
+func getRunJobs(run *v1a1.ApacheBenchRun) []string {
+	if run.Capacity == nil || len(run.Capacity.Iterations) <= 0 {
+		return []string{run.Job}
+	}
+
+	jobs := make([]string, 0)
+	for _, iteration := range run.Capacity.Iterations {
+		jobs = append(jobs, iteration.Job)
+	}
+	return jobs
+}
+
+// getRunTrigger will return the reason to start a new run for the given ApacheBench, or an empty string if a new run
+// is not needed. The given spec hash is compared with the hash for the most recent run. When the run is triggered by
+// the Schedule, the scheduled time is also returned.
+func getRunTrigger(cr *v1a1.ApacheBench, specHash string, now time.Time) (string, *metav1.Time, error) {
+	current := cr.GetCurrentRun()
+	if current != nil && !isRunFinished(current) {
+		return "", nil, nil // Wait for the current run to finish
+	}
+
+	if len(cr.Spec.Schedule) > 0 {
+		sched, err := parseSchedule(cr.Spec.Schedule)
+		if err != nil {
+			return "", nil, err
+		}
+
+		earliest := cr.CreationTimestamp.Time
+		if cr.Status.LastScheduleTime != nil {
+			earliest = cr.Status.LastScheduleTime.Time
+		}
+
+		scheduled := sched.mostRecent(earliest, now)
+		if !scheduled.IsZero() {
+			return runTriggerSchedule, &metav1.Time{Time: scheduled}, nil
+		}
+	} else if current == nil {
+		return runTriggerInitial, nil, nil
+	}
+
+	// The run ID annotation is compared with the most recent run, so that setting it on a new ApacheBench does not
+	// start a second run.
+	runID := cr.Annotations[v1a1.ApacheBenchRunIDAnnotation]
+	if len(runID) > 0 && (current == nil || current.RunID != runID) {
+		return runTriggerManual, nil, nil
+	}
+
+	// Runs from before the spec hash was recorded are not considered to have drifted.
+	if current != nil && len(current.SpecHash) > 0 && current.SpecHash != specHash {
+		return runTriggerSpecChange, nil, nil
+	}
+
+	return "", nil, nil
+}
+
+// getSpecHash will return a hash of the spec for the given ApacheBench, along with the given data from referenced
+// Secret and ConfigMap keys. Properties that only control when the benchmark runs, or what is done with the results,
+// rather than how the benchmark runs, are not included in the hash.
+func getSpecHash(cr *v1a1.ApacheBench, references [][]byte) string {
+	spec := cr.Spec.DeepCopy()
+	spec.Baseline = nil
+	spec.Output = nil
+	spec.Readiness = nil
+	spec.RerunOnReferenceChange = false
+	spec.RunHistoryLimit = nil
+	spec.Schedule = ""
+	spec.Thresholds = nil
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return ""
+	}
+
+	hasher := fnv.New32a()
+	hasher.Write(data)
+	for _, ref := range references {
+		hasher.Write([]byte{0}) // Separate the values, so that moving data between keys changes the hash
+		hasher.Write(ref)
+	}
+	return fmt.Sprintf("%08x", hasher.Sum32())
+}
+
+// isRunFinished will return true if the given run has either completed or failed.
+func isRunFinished(run *v1a1.ApacheBenchRun) bool {
+	return run.Phase == v1a1.ApacheBenchPhaseComplete || run.Phase == v1a1.ApacheBenchPhaseFailed
+}
+
+// newRun will add a new run with the given trigger and spec hash to the status of the given ApacheBench and return it.
+// The errors from the previous run are cleared, so that the errors in the status only describe the current run.
+func newRun(cr *v1a1.ApacheBench, trigger string, scheduled *metav1.Time, specHash string) *v1a1.ApacheBenchRun {
+	number := int32(1)
+	if current := cr.GetCurrentRun(); current != nil {
+		number = current.Number + 1
+	}
+
+	now := metav1.Now()
+	cr.Status.Runs = append(cr.Status.Runs, v1a1.ApacheBenchRun{
+		Job:           getRunJobName(cr, number),
+		Number:        number,
+		Phase:         v1a1.ApacheBenchPhasePending,
+		RunID:         cr.Annotations[v1a1.ApacheBenchRunIDAnnotation],
+		ScheduledTime: scheduled,
+		SpecHash:      specHash,
+		StartTime:     &now,
+		Trigger:       trigger,
+	})
+
+	if scheduled != nil {
+		cr.Status.LastScheduleTime = scheduled
+	}
+	cr.Status.Errors = nil
+
+	return cr.GetCurrentRun()
+}
+
+// startRun will start a new run with the given trigger and spec hash for the given ApacheBench.
+func (r *ReconcileApacheBench) startRun(cr *v1a1.ApacheBench, trigger string, scheduled *metav1.Time, specHash string) error {
+	run := newRun(cr, trigger, scheduled, specHash)
+	if isCapacitySearch(cr) {
+		concurrency, _ := getNextConcurrency(cr, nil)
+		newIteration(cr, run, concurrency)
+	}
+	log.Info("starting run", "namespace", cr.Namespace, "name", cr.Name, "run", run.Number, "trigger", trigger)
+	cr.Status.Phase = run.Phase
+	resetConditions(cr, "RunStarted", fmt.Sprintf("started run %d (%s)", run.Number, trigger))
+
+	// The run history is still trimmed when the Job cannot be created, as the run has been recorded as failed.
+	createErr := r.createJob(cr, run)
+	if createErr != nil && !isJobCreationError(createErr) {
+		return createErr
+	}
+
+	if err := r.trimRunHistory(cr); err != nil {
+		return err
+	}
+
+	if err := r.client.Status().Update(context.TODO(), cr); err != nil {
+		return err
+	}
+	return createErr
+}
+
+// trimRunHistory will remove the oldest runs, along with their Jobs and stored results, that exceed the history limit
+// for the given ApacheBench.
+func (r *ReconcileApacheBench) trimRunHistory(cr *v1a1.ApacheBench) error {
+	limit := getRunHistoryLimit(cr)
+
+	for len(cr.Status.Runs) > limit {
+		for _, name := range getRunJobs(&cr.Status.Runs[0]) {
+			job := newJob(cr, name)
+			err := r.client.Delete(context.TODO(), job, client.PropagationPolicy(metav1.DeletePropagationBackground))
+			if err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+		cr.Status.Runs = cr.Status.Runs[1:]
+	}
+
+	return nil
+}
+
+// updateRunStatus will update the given run, along with the status of the given ApacheBench, using the state of the
+// given Job and its Pods. The results are added to the status once the Job has completed.
+func (r *ReconcileApacheBench) updateRunStatus(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, job *batchv1.Job) error {
+	if cond := getJobCondition(job, batchv1.JobFailed); cond != nil {
+		run.Phase = v1a1.ApacheBenchPhaseFailed
+		run.CompletionTime = &cond.LastTransitionTime
+		cr.Status.Phase = run.Phase
+
+		msg := fmt.Sprintf("job '%s' failed: %s", job.Name, cond.Message)
+		cr.Status.AddError(msg)
+		setCondition(cr, v1a1.ApacheBenchConditionRunning, corev1.ConditionFalse, "JobFailed", msg)
+		setCondition(cr, v1a1.ApacheBenchConditionSucceeded, corev1.ConditionFalse, "JobFailed", msg)
+		setCondition(cr, v1a1.ApacheBenchConditionFailed, corev1.ConditionTrue, cond.Reason, msg)
+		return nil
+	}
+
+	if cond := getJobCondition(job, batchv1.JobComplete); cond != nil {
+		// Add results to the CR status, a capacity search continues with the next iteration until it is complete
+		if isCapacitySearch(cr) {
+			if done, err := r.updateCapacitySearch(cr, run, job); err != nil || !done {
+				return err
+			}
+		} else if err := r.addJobResultsToStatus(cr, run, job); err != nil {
+			return err
+		}
+
+		run.Aggregate = cr.Status.Aggregate
+		run.Endpoints = cr.Status.Endpoints
+		run.Results = cr.Status.ResultsRefs
+		run.Stages = cr.Status.Stages
+		cr.Status.Capacity = run.Capacity.DeepCopy()
+		run.Phase = v1a1.ApacheBenchPhaseComplete
+		run.CompletionTime = job.Status.CompletionTime
+		cr.Status.Phase = run.Phase
+
+		msg := fmt.Sprintf("job '%s' completed", job.Name)
+		if run.Capacity != nil {
+			msg = fmt.Sprintf("capacity search completed after %d iteration(s), the maximum sustainable concurrency is %d",
+				len(run.Capacity.Iterations), run.Capacity.Concurrency)
+		}
+		setCondition(cr, v1a1.ApacheBenchConditionRunning, corev1.ConditionFalse, "JobComplete", msg)
+		setCondition(cr, v1a1.ApacheBenchConditionSucceeded, corev1.ConditionTrue, "JobComplete", msg)
+		setCondition(cr, v1a1.ApacheBenchConditionFailed, corev1.ConditionFalse, "JobComplete", msg)
+
+		if cr.Spec.HTML.Enabled || len(cr.Status.Summary) == len(cr.Status.ResultsRefs) {
+			setCondition(cr, v1a1.ApacheBenchConditionResultsCollected, corev1.ConditionTrue, "ResultsParsed",
+				fmt.Sprintf("collected results from %d pod(s)", len(cr.Status.ResultsRefs)))
+		} else {
+			setCondition(cr, v1a1.ApacheBenchConditionResultsCollected, corev1.ConditionFalse, "ParseFailed",
+				fmt.Sprintf("unable to parse results from %d of %d pod(s)",
+					len(cr.Status.ResultsRefs)-len(cr.Status.Summary), len(cr.Status.ResultsRefs)))
+		}
+		return nil
+	}
+
+	pods, err := r.getJobPods(job)
+	if err != nil {
+		return err
+	}
+
+	run.Phase = v1a1.ApacheBenchPhasePending
+	reason, msg := "PodsPending", fmt.Sprintf("waiting for pods for job '%s' to start", job.Name)
+	for _, pod := range pods {
+		if waitReason, waitMsg := getPodWaitingReason(pod); len(waitReason) > 0 {
+			reason, msg = waitReason, fmt.Sprintf("pod '%s' is waiting: %s", pod.Name, waitMsg)
+			break
+		}
+		if isProbingReadiness(pod) {
+			if isReadinessTimeoutExceeded(cr, run) {
+				return r.failReadinessProbe(cr, run, job, pod)
+			}
+			reason, msg = readinessProbeReason, fmt.Sprintf("pod '%s' is waiting for the readiness probe to succeed", pod.Name)
+			continue
+		}
+		if isWarmingUp(pod) {
+			run.Phase = v1a1.ApacheBenchPhaseRunning
+			reason, msg = "WarmingUp", fmt.Sprintf("pod '%s' is running the warm-up", pod.Name)
+			continue
+		}
+		if pod.Status.Phase == corev1.PodRunning {
+			run.Phase = v1a1.ApacheBenchPhaseRunning
+			reason, msg = "PodsRunning", fmt.Sprintf("pods for job '%s' are running", job.Name)
+		}
+	}
+	cr.Status.Phase = run.Phase
+
+	status := corev1.ConditionFalse
+	if run.Phase == v1a1.ApacheBenchPhaseRunning {
+		status = corev1.ConditionTrue
+	}
+	setCondition(cr, v1a1.ApacheBenchConditionRunning, status, reason, msg)
+	return nil
+}
+
+// getPodWaitingReason will return the reason and message when the given Pod is unable to start, for example when
+// the container image cannot be pulled or the Pod cannot be scheduled. Empty strings are returned otherwise.
+func getPodWaitingReason(pod corev1.Pod) (string, string) {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse && len(cond.Reason) > 0 {
+			return cond.Reason, cond.Message
+		}
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting == nil {
+			continue
+		}
+
+		switch cs.State.Waiting.Reason {
+		case "", "ContainerCreating", "PodInitializing":
+			continue // Normal startup
+		}
+
+		msg := cs.State.Waiting.Message
+		if len(msg) <= 0 {
+			msg = cs.State.Waiting.Reason
+		}
+		return cs.State.Waiting.Reason, msg
+	}
+
+	return "", ""
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultS3Region is the region used to sign requests when not specified.
+	defaultS3Region = "us-east-1"
+
+	// s3Timeout is the time to wait for each upload to complete.
+	s3Timeout = 60 * time.Second
+)
+
+// s3Client is a minimal client for S3-compatible object storage, that supports uploading objects using path-style
+// requests signed with AWS Signature Version 4.
+type s3Client struct {
+	accessKeyID     string
+	endpoint        string
+	httpClient      *http.Client
+	region          string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// newS3Client returns a new client for the S3-compatible service at the given endpoint.
+func newS3Client(endpoint string, region string, accessKeyID string, secretAccessKey string, sessionToken string) *s3Client {
+	if len(region) <= 0 {
+		region = defaultS3Region
+	}
+
+	return &s3Client{
+		accessKeyID:     accessKeyID,
+		endpoint:        strings.TrimRight(endpoint, "/"),
+		httpClient:      &http.Client{Timeout: s3Timeout},
+		region:          region,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+	}
+}
+
+// putObject will upload the given data to the object with the given key in the given bucket.
+func (c *s3Client) putObject(bucket string, key string, data []byte, contentType string) error {
+	u, err := url.Parse(fmt.Sprintf("%s/%s/%s", c.endpoint, bucket, s3EscapePath(key)))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if len(c.sessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	c.sign(req, data, time.Now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status code %d while uploading %s: %s", resp.StatusCode, key, string(body))
+	}
+	return nil
+}
+
+// sign will add the headers to the given request to sign it using AWS Signature Version 4. Every header that is set
+// on the request is signed, along with the host.
+func (c *s3Client) sign(req *http.Request, payload []byte, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	payloadHash := sha256.Sum256(payload)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0)
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	canonicalHeaders := ""
+	for _, name := range names {
+		canonicalHeaders += name + ":" + headers[name] + "\n"
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := fmt.Sprintf("%s/%s/s3/aws4_request", date, c.region)
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := s3HMAC([]byte("AWS4"+c.secretAccessKey), date)
+	key = s3HMAC(key, c.region)
+	key = s3HMAC(key, "s3")
+	key = s3HMAC(key, "aws4_request")
+	signature := hex.EncodeToString(s3HMAC(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKeyID, scope, signedHeaders, signature))
+}
+
+// s3EscapePath will escape each segment of the given object key, as required for the canonical request.
+// Only the unreserved characters are left unescaped.
+func s3EscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		escaped := ""
+		for _, b := range []byte(segment) {
+			switch {
+			case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+				escaped += string(b)
+			default:
+				escaped += fmt.Sprintf("%%%02X", b)
+			}
+		}
+		segments[i] = escaped
+	}
+	return strings.Join(segments, "/")
+}
+
+// s3HMAC will return the HMAC-SHA256 of the given data using the given key.
+func s3HMAC(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,249 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scheduleDescriptors maps the supported descriptors to the equivalent cron expression.
+var scheduleDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// scheduleField defines the bounds and names for a single field of a cron expression.
+type scheduleField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var (
+	minuteField     = scheduleField{name: "minute", min: 0, max: 59}
+	hourField       = scheduleField{name: "hour", min: 0, max: 23}
+	dayOfMonthField = scheduleField{name: "day of month", min: 1, max: 31}
+	monthField      = scheduleField{name: "month", min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dayOfWeekField = scheduleField{name: "day of week", min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// schedule is a parsed cron expression. Each field is stored as a bit set of the matching values.
+type schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+
+	// anyDay is true when either day field is a wildcard, in which case both day fields must match.
+	// Otherwise a time matches when either day field matches, as in the standard cron implementation.
+	anyDay bool
+}
+
+// matchesDay will return true if the day of the given time matches the schedule.
+func (s *schedule) matchesDay(t time.Time) bool {
+	dom := s.dayOfMonth&(1<<uint(t.Day())) > 0
+	dow := s.dayOfWeek&(1<<uint(t.Weekday())) > 0
+	if s.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next will return the first time matching the schedule that is after the given time.
+// A zero time is returned if there is no matching time within the next five years.
+func (s *schedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// mostRecent will return the most recent time matching the schedule that is after the given earliest time and not
+// after the given now time. A zero time is returned if there is no such time. The search starts from the given now
+// time and works backwards, so that the result does not depend on how long ago the earliest time was.
+func (s *schedule) mostRecent(earliest time.Time, now time.Time) time.Time {
+	t := s.previous(now)
+	if t.IsZero() || !t.After(earliest) {
+		return time.Time{}
+	}
+	return t
+}
+
+// previous will return the last time matching the schedule that is not after the given time.
+// A zero time is returned if there is no matching time within the previous five years.
+func (s *schedule) previous(t time.Time) time.Time {
+	loc := t.Location()
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+	limit := t.AddDate(-5, 0, 0)
+
+	for !t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc).Add(-time.Minute)
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc).Add(-time.Minute)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc).Add(-time.Minute)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(-time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// parseSchedule will parse the given cron expression.
+func parseSchedule(spec string) (*schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if expr, ok := scheduleDescriptors[strings.ToLower(spec)]; ok {
+		spec = expr
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in schedule '%s', found %d", spec, len(fields))
+	}
+
+	s := &schedule{}
+	var err error
+
+	if s.minute, err = parseScheduleField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseScheduleField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if s.dayOfMonth, err = parseScheduleField(fields[2], dayOfMonthField); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseScheduleField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if s.dayOfWeek, err = parseScheduleField(fields[4], dayOfWeekField); err != nil {
+		return nil, err
+	}
+
+	// Sunday may be given as either 0 or 7.
+	if s.dayOfWeek&(1<<7) > 0 {
+		s.dayOfWeek |= 1
+	}
+
+	s.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+	return s, nil
+}
+
+// parseScheduleField will parse a single field of a cron expression into a bit set of the matching values.
+// Each comma-separated part of the field may be a wildcard, a value or a range, optionally followed by a step.
+func parseScheduleField(value string, field scheduleField) (uint64, error) {
+	bits := uint64(0)
+
+	for _, part := range strings.Split(value, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step '%s' for %s", part[idx+1:], field.name)
+			}
+			part = part[:idx]
+		}
+
+		start, end := field.min, field.max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if start, err = parseScheduleValue(bounds[0], field); err != nil {
+				return 0, err
+			}
+
+			end = start
+			if len(bounds) > 1 {
+				if end, err = parseScheduleValue(bounds[1], field); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				end = field.max
+			}
+
+			if end < start {
+				return 0, fmt.Errorf("invalid range '%s' for %s", part, field.name)
+			}
+		}
+
+		for i := start; i <= end; i += step {
+			bits |= 1 << uint(i)
+		}
+	}
+
+	return bits, nil
+}
+
+// parseScheduleValue will parse a single value, either a number or a name, for the given field.
+func parseScheduleValue(value string, field scheduleField) (int, error) {
+	if v, ok := field.names[strings.ToLower(value)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil || v < field.min || v > field.max {
+		return 0, fmt.Errorf("invalid value '%s' for %s", value, field.name)
+	}
+
+	return v, nil
+}
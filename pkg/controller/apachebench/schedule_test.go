@@ -0,0 +1,212 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"testing"
+	"time"
+)
+
+// mustParseTime will parse the given RFC 3339 time, failing the test if it is invalid.
+func mustParseTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatalf("invalid time '%s': %v", value, err)
+	}
+	return parsed
+}
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+		want    schedule
+	}{
+		{
+			name: "every minute",
+			spec: "* * * * *",
+			want: schedule{minute: 1<<60 - 1, hour: 1<<24 - 1, dayOfMonth: 1<<32 - 2, month: 1<<13 - 2, dayOfWeek: 1<<8 - 1, anyDay: true},
+		},
+		{
+			name: "list, range and step",
+			spec: "0,30 9-17/4 1 * mon-fri",
+			want: schedule{minute: 1 | 1<<30, hour: 1<<9 | 1<<13 | 1<<17, dayOfMonth: 1 << 1, month: 1<<13 - 2, dayOfWeek: 0x3e},
+		},
+		{
+			name: "step from a value",
+			spec: "10/20 * * * *",
+			want: schedule{minute: 1<<10 | 1<<30 | 1<<50, hour: 1<<24 - 1, dayOfMonth: 1<<32 - 2, month: 1<<13 - 2, dayOfWeek: 1<<8 - 1, anyDay: true},
+		},
+		{
+			name: "sunday as seven",
+			spec: "0 0 * * 7",
+			want: schedule{minute: 1, hour: 1, dayOfMonth: 1<<32 - 2, month: 1<<13 - 2, dayOfWeek: 1 | 1<<7, anyDay: true},
+		},
+		{
+			name: "both day fields restricted",
+			spec: "0 0 13 * fri",
+			want: schedule{minute: 1, hour: 1, dayOfMonth: 1 << 13, month: 1<<13 - 2, dayOfWeek: 1 << 5},
+		},
+		{
+			name: "descriptor",
+			spec: "@daily",
+			want: schedule{minute: 1, hour: 1, dayOfMonth: 1<<32 - 2, month: 1<<13 - 2, dayOfWeek: 1<<8 - 1, anyDay: true},
+		},
+		{name: "too few fields", spec: "* * * *", wantErr: true},
+		{name: "value out of range", spec: "60 * * * *", wantErr: true},
+		{name: "unknown name", spec: "* * * foo *", wantErr: true},
+		{name: "reversed range", spec: "* 10-5 * * *", wantErr: true},
+		{name: "invalid step", spec: "*/0 * * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSchedule(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseSchedule(%q) expected an error", tt.spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseSchedule(%q) returned an error: %v", tt.spec, err)
+			}
+			if *got != tt.want {
+				t.Errorf("parseSchedule(%q) = %+v, want %+v", tt.spec, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+		from string
+		want string
+	}{
+		{name: "every minute", spec: "* * * * *", from: "2026-10-17T10:15:30Z", want: "2026-10-17T10:16:00Z"},
+		{name: "exact match is skipped", spec: "*/15 * * * *", from: "2026-10-17T10:15:00Z", want: "2026-10-17T10:30:00Z"},
+		{name: "next hour", spec: "5 * * * *", from: "2026-10-17T10:15:00Z", want: "2026-10-17T11:05:00Z"},
+		{name: "next day", spec: "0 9 * * *", from: "2026-10-17T10:00:00Z", want: "2026-10-18T09:00:00Z"},
+		{name: "next weekday", spec: "0 0 * * mon", from: "2026-10-17T10:00:00Z", want: "2026-10-19T00:00:00Z"},
+		{name: "next year", spec: "@yearly", from: "2026-10-17T10:00:00Z", want: "2027-01-01T00:00:00Z"},
+		{name: "either day field", spec: "0 0 13 * fri", from: "2026-10-17T10:00:00Z", want: "2026-10-23T00:00:00Z"},
+		{name: "leap day", spec: "0 0 29 2 *", from: "2026-10-17T10:00:00Z", want: "2028-02-29T00:00:00Z"},
+		{name: "no match", spec: "0 0 31 2 *", from: "2026-10-17T10:00:00Z", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := parseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("parseSchedule(%q) returned an error: %v", tt.spec, err)
+			}
+
+			got := sched.next(mustParseTime(t, tt.from))
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("next(%s) = %s, want zero time", tt.from, got)
+				}
+				return
+			}
+			if want := mustParseTime(t, tt.want); !got.Equal(want) {
+				t.Errorf("next(%s) = %s, want %s", tt.from, got, want)
+			}
+		})
+	}
+}
+
+func TestScheduleMostRecent(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		earliest string
+		now      string
+		want     string
+	}{
+		{
+			name:     "earliest long ago",
+			spec:     "* * * * *",
+			earliest: "2025-10-17T00:00:00Z",
+			now:      "2026-10-17T10:15:30Z",
+			want:     "2026-10-17T10:15:00Z",
+		},
+		{
+			name:     "now matches",
+			spec:     "*/15 * * * *",
+			earliest: "2026-10-17T09:00:00Z",
+			now:      "2026-10-17T10:15:00Z",
+			want:     "2026-10-17T10:15:00Z",
+		},
+		{
+			name:     "missed several",
+			spec:     "0 * * * *",
+			earliest: "2026-10-17T06:00:00Z",
+			now:      "2026-10-17T10:15:00Z",
+			want:     "2026-10-17T10:00:00Z",
+		},
+		{
+			name:     "previous month",
+			spec:     "0 0 1 * *",
+			earliest: "2026-09-01T00:00:00Z",
+			now:      "2026-10-17T10:15:00Z",
+			want:     "2026-10-01T00:00:00Z",
+		},
+		{
+			name:     "previous year",
+			spec:     "0 12 25 dec *",
+			earliest: "2024-01-01T00:00:00Z",
+			now:      "2026-10-17T10:15:00Z",
+			want:     "2025-12-25T12:00:00Z",
+		},
+		{
+			name:     "earliest is excluded",
+			spec:     "0 * * * *",
+			earliest: "2026-10-17T10:00:00Z",
+			now:      "2026-10-17T10:59:00Z",
+			want:     "",
+		},
+		{
+			name:     "none since earliest",
+			spec:     "0 0 * * *",
+			earliest: "2026-10-17T00:30:00Z",
+			now:      "2026-10-17T10:15:00Z",
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sched, err := parseSchedule(tt.spec)
+			if err != nil {
+				t.Fatalf("parseSchedule(%q) returned an error: %v", tt.spec, err)
+			}
+
+			got := sched.mostRecent(mustParseTime(t, tt.earliest), mustParseTime(t, tt.now))
+			if tt.want == "" {
+				if !got.IsZero() {
+					t.Errorf("mostRecent(%s, %s) = %s, want zero time", tt.earliest, tt.now, got)
+				}
+				return
+			}
+			if want := mustParseTime(t, tt.want); !got.Equal(want) {
+				t.Errorf("mostRecent(%s, %s) = %s, want %s", tt.earliest, tt.now, got, want)
+			}
+		})
+	}
+}
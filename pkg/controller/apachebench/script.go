@@ -0,0 +1,250 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+const (
+	// authPasswordEnv is the environment variable in the benchmark container that contains the password for the request.
+	authPasswordEnv = "AB_AUTH_PASSWORD"
+
+	// authUsernameEnv is the environment variable in the benchmark container that contains the username for the request.
+	authUsernameEnv = "AB_AUTH_USERNAME"
+
+	// cookieNameEnvPrefix is the prefix of the environment variables in the benchmark container that contain the name
+	// of each cookie from a Secret or ConfigMap key.
+	cookieNameEnvPrefix = "AB_COOKIE_NAME_"
+
+	// cookieValueEnvPrefix is the prefix of the environment variables in the benchmark container that contain the
+	// value of each cookie from a Secret or ConfigMap key.
+	cookieValueEnvPrefix = "AB_COOKIE_VALUE_"
+
+	// headerNameEnvPrefix is the prefix of the environment variables in the benchmark container that contain the name
+	// of each header from a Secret or ConfigMap key.
+	headerNameEnvPrefix = "AB_HEADER_NAME_"
+
+	// headerValueEnvPrefix is the prefix of the environment variables in the benchmark container that contain the
+	// value of each header from a Secret or ConfigMap key.
+	headerValueEnvPrefix = "AB_HEADER_VALUE_"
+
+	// proxyPasswordEnv is the environment variable in the benchmark container that contains the password for the proxy.
+	proxyPasswordEnv = "AB_PROXY_PASSWORD"
+
+	// proxyUsernameEnv is the environment variable in the benchmark container that contains the username for the proxy.
+	proxyUsernameEnv = "AB_PROXY_USERNAME"
+
+	// resultsDirEnv is the environment variable in the benchmark container that contains the directory to write the
+	// results for the run to.
+	resultsDirEnv = "RESULTS_DIR"
+
+	// resultsMountPath is the path that the results volume is mounted at in the benchmark container.
+	resultsMountPath = "/results"
+)
+
+// getContainerCommand will return the command for the benchmark container that runs the given ab command for the given
+// run. A shell script is used to run ab when credentials, values from Secret or ConfigMap keys, stages, endpoints or
+// files other than the output are needed, otherwise ab is run directly.
+func getContainerCommand(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, cmd []string) []string {
+	if !useScript(cr) {
+		return cmd
+	}
+
+	// The ab command is passed as the positional parameters, so that none of the values are interpreted by the shell.
+	return append([]string{"/bin/sh", "-c", getScript(cr, run)}, cmd...)
+}
+
+// getResultsDir will return the directory in the benchmark container to write the results for the given run to.
+func getResultsDir(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) string {
+	return path.Join(resultsMountPath, cr.Name, strconv.Itoa(int(run.Number)))
+}
+
+// getScript will return the shell script that runs ab in the benchmark container. Any credentials, headers and cookies
+// from Secret or ConfigMap keys are added to the ab command from the environment, so that they are not visible in the
+// Pod spec. When files other than the output are needed, the output is written to a file and then printed, followed by
+// a section for each of the enabled gnuplot and CSV files, so that the files can be collected from the Pod logs. When a
+// volume claim is set, the files are written to the volume. When there are stages, each stage is run in turn until one
+// fails, and the output for each stage is printed in its own section. When there are endpoints, each endpoint of the
+// given run is run in turn or in parallel, and the output for each endpoint is printed in its own section.
+func getScript(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) string {
+	lines := make([]string, 0)
+
+	// The values are added before the other arguments, so that the URL remains last.
+	for i := range cr.Spec.CookiesFrom {
+		lines = append(lines, fmt.Sprintf(`set -- -C "$%s%d=$%s%d" "$@"`, cookieNameEnvPrefix, i, cookieValueEnvPrefix, i))
+	}
+	for i := range cr.Spec.HeadersFrom {
+		lines = append(lines, fmt.Sprintf(`set -- -H "$%s%d: $%s%d" "$@"`, headerNameEnvPrefix, i, headerValueEnvPrefix, i))
+	}
+	if cr.Spec.AuthenticateProxy {
+		lines = append(lines, fmt.Sprintf(`set -- -P "$%s:$%s" "$@"`, proxyUsernameEnv, proxyPasswordEnv))
+	}
+	if cr.Spec.Authenticate {
+		lines = append(lines, fmt.Sprintf(`set -- -A "$%s:$%s" "$@"`, authUsernameEnv, authPasswordEnv))
+	}
+
+	volume := cr.Spec.Output != nil && cr.Spec.Output.VolumeClaim != nil
+	if !volume && !cr.Spec.CSV && !cr.Spec.Gnuplot && len(cr.Spec.Stages) <= 0 && len(cr.Spec.Endpoints) <= 0 {
+		return strings.Join(append(lines, `exec ab "$@"`), "\n")
+	}
+
+	if volume {
+		lines = append(lines,
+			fmt.Sprintf(`mkdir -p "$%s"`, resultsDirEnv),
+			fmt.Sprintf(`out="$%s/$HOSTNAME"`, resultsDirEnv),
+		)
+	} else {
+		lines = append(lines, `out="/tmp/$HOSTNAME"`)
+	}
+
+	ext := "txt"
+	if cr.Spec.HTML.Enabled {
+		ext = "html"
+	}
+
+	file := "$out"
+	files := []string{file}
+	switch {
+	case len(cr.Spec.Endpoints) > 0:
+		files = make([]string, 0)
+		for i := range cr.Spec.Endpoints {
+			files = append(files, fmt.Sprintf("$out-%s", getEndpointSection(i)))
+		}
+		lines = append(append(lines, "rc=0"), getEndpointScript(cr, run, volume, files, ext)...)
+	case len(cr.Spec.Stages) > 0:
+		lines = append(lines, "rc=0")
+		for i, stage := range cr.Spec.Stages {
+			file = fmt.Sprintf("$out-%s", getStageSection(i))
+			lines = append(lines,
+				"if [ $rc -eq 0 ]; then",
+				fmt.Sprintf(`  %s %s "$@" > "%s.%s" 2>&1`, getScriptCommand(cr, volume, file), getStageArgs(stage), file, ext),
+				"  rc=$?",
+				fmt.Sprintf(`  echo "%s%s%s"`, outputSectionPrefix, getStageSection(i), outputSectionSuffix),
+				fmt.Sprintf(`  cat "%s.%s"`, file, ext),
+				"fi",
+			)
+		}
+		files = []string{file}
+	default:
+		lines = append(lines,
+			fmt.Sprintf(`%s "$@" > "%s.%s" 2>&1`, getScriptCommand(cr, volume, file), file, ext),
+			"rc=$?",
+			fmt.Sprintf(`cat "%s.%s"`, file, ext),
+		)
+	}
+
+	// The gnuplot and CSV files are collected for the final stage only. The gnuplot files for every endpoint are
+	// combined without the repeated headers, while the CSV files for the endpoints are only written to the volume.
+	if cr.Spec.Gnuplot {
+		commands := make([]string, 0)
+		paths := make([]string, 0)
+		for i, f := range files {
+			if i == 0 {
+				commands = append(commands, fmt.Sprintf(`cat "%s.tsv"`, f))
+			} else {
+				commands = append(commands, fmt.Sprintf(`tail -n +2 "%s.tsv"`, f))
+			}
+			paths = append(paths, fmt.Sprintf(`"%s.tsv"`, f))
+		}
+		lines = append(lines, getFileSectionScript(gnuplotSection, paths, commands)...)
+	}
+	if cr.Spec.CSV && len(files) == 1 {
+		path := fmt.Sprintf(`"%s.csv"`, file)
+		lines = append(lines, getFileSectionScript(csvSection, []string{path}, []string{"cat " + path})...)
+	}
+
+	return strings.Join(append(lines, "exit $rc"), "\n")
+}
+
+// getEndpointScript will return the lines of the script that run ab for each endpoint of the given run of the given
+// ApacheBench, writing the output to the given files, and then print the output for each endpoint in its own section.
+// The exit code of the script is set when ab fails for any endpoint.
+func getEndpointScript(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun, volume bool, files []string, ext string) []string {
+	lines := make([]string, 0)
+
+	for i := range cr.Spec.Endpoints {
+		ab := fmt.Sprintf(`%s %s > "%s.%s" 2>&1`, getScriptCommand(cr, volume, files[i]), getEndpointArgs(cr, run, i), files[i], ext)
+		if isParallelEndpoints(cr) {
+			lines = append(lines, ab+" &", fmt.Sprintf("pid%d=$!", i+1))
+		} else {
+			lines = append(lines, ab+" || rc=$?")
+		}
+	}
+
+	for i := range cr.Spec.Endpoints {
+		if isParallelEndpoints(cr) {
+			lines = append(lines, fmt.Sprintf("wait $pid%d || rc=$?", i+1))
+		}
+		lines = append(lines,
+			fmt.Sprintf(`echo "%s%s%s"`, outputSectionPrefix, getEndpointSection(i), outputSectionSuffix),
+			fmt.Sprintf(`cat "%s.%s"`, files[i], ext),
+		)
+	}
+
+	return lines
+}
+
+// getFileSectionScript will return the lines of the script that print the section with the given name using the
+// given commands, for the files with the given quoted paths. When the files are larger than maxFileSectionSize, only
+// their size is printed in the skipped section for the name instead, so that the Pod logs are not rotated before the
+// results are collected.
+func getFileSectionScript(name string, paths []string, commands []string) []string {
+	lines := []string{
+		fmt.Sprintf(`size=$(cat %s | wc -c)`, strings.Join(paths, " ")),
+		fmt.Sprintf(`if [ $size -le %d ]; then`, maxFileSectionSize),
+		fmt.Sprintf(`  echo "%s%s%s"`, outputSectionPrefix, name, outputSectionSuffix),
+	}
+	for _, command := range commands {
+		lines = append(lines, "  "+command)
+	}
+	return append(lines,
+		"else",
+		fmt.Sprintf(`  echo "%s%s%s%s"`, outputSectionPrefix, name, skippedSectionSuffix, outputSectionSuffix),
+		`  echo $size`,
+		"fi",
+	)
+}
+
+// getScriptCommand will return the ab command for the script, that writes any enabled gnuplot and CSV files using the
+// given file name without the extension.
+func getScriptCommand(cr *v1a1.ApacheBench, volume bool, file string) string {
+	ab := "ab"
+	if volume || cr.Spec.Gnuplot {
+		ab += fmt.Sprintf(` -g "%s.tsv"`, file)
+	}
+	if volume || cr.Spec.CSV {
+		ab += fmt.Sprintf(` -e "%s.csv"`, file)
+	}
+	return ab
+}
+
+// getVolumePath will return the directory within the results volume that the results for the given run of the given
+// ApacheBench are written to.
+func getVolumePath(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) string {
+	return path.Join(cr.Spec.Output.VolumeClaim.SubPath, cr.Name, strconv.Itoa(int(run.Number)))
+}
+
+// useScript will return true if the benchmark container for the given ApacheBench needs a shell script to run ab.
+func useScript(cr *v1a1.ApacheBench) bool {
+	return cr.Spec.Authenticate || cr.Spec.AuthenticateProxy || len(cr.Spec.CookiesFrom) > 0 ||
+		len(cr.Spec.Endpoints) > 0 || len(cr.Spec.HeadersFrom) > 0 || len(cr.Spec.Stages) > 0 || cr.Spec.CSV ||
+		cr.Spec.Gnuplot || (cr.Spec.Output != nil && cr.Spec.Output.VolumeClaim != nil)
+}
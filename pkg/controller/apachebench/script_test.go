@@ -0,0 +1,110 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+// stubFilesAB is a stand-in for ab that writes a gnuplot file of $TSV_SIZE bytes and a small CSV file.
+const stubFilesAB = `#!/bin/sh
+while [ $# -gt 0 ]; do
+  case "$1" in
+  -g) head -c "$TSV_SIZE" /dev/zero | tr '\0' '\n' > "$2"; shift ;;
+  -e) echo "Percentage served,Time in ms" > "$2"; shift ;;
+  esac
+  shift
+done
+echo "Requests per second:    485.44 [#/sec] (mean)"
+`
+
+// runTestScript will run the given script with the given stand-in for ab and the given environment, and return the
+// Pod logs. The test is skipped when a shell is not available.
+func runTestScript(t *testing.T, script string, ab string, env ...string) string {
+	t.Helper()
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	dir, err := ioutil.TempDir("/tmp", "script")
+	if err != nil {
+		t.Skipf("unable to create a directory in /tmp: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "ab"), []byte(ab), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(sh, "-c", script, "ab", "http://example.com/")
+	cmd.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"), "HOSTNAME="+filepath.Base(dir)+"/pod")
+	cmd.Env = append(cmd.Env, env...)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("script returned an error: %v\n%s", err, out)
+	}
+	return string(out)
+}
+
+func TestGetScriptFileSections(t *testing.T) {
+	cr := &v1a1.ApacheBench{Spec: v1a1.ApacheBenchSpec{CSV: true, Gnuplot: true}}
+	script := getScript(cr, &v1a1.ApacheBenchRun{Number: 1})
+
+	tests := []struct {
+		name        string
+		size        int
+		wantSkipped bool
+	}{
+		{
+			name: "within the limit",
+			size: maxFileSectionSize,
+		},
+		{
+			name:        "above the limit",
+			size:        maxFileSectionSize + 1,
+			wantSkipped: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := runTestScript(t, script, stubFilesAB, "TSV_SIZE="+strconv.Itoa(tt.size))
+			output, sections := splitOutputSections(logs)
+			if !strings.HasPrefix(output, "Requests per second:") {
+				t.Errorf("output = %q, want the ab output", output)
+			}
+			if got, want := sections[csvSection], "Percentage served,Time in ms\n"; got != want {
+				t.Errorf("csv section = %q, want %q", got, want)
+			}
+
+			gnuplot, ok := sections[gnuplotSection]
+			skipped, skippedOK := sections[gnuplotSection+skippedSectionSuffix]
+			if tt.wantSkipped {
+				if ok || !skippedOK || strings.TrimSpace(skipped) != strconv.Itoa(tt.size) {
+					t.Errorf("gnuplot section printed = %t, skipped section = %q, want only the size", ok, skipped)
+				}
+			} else if !ok || skippedOK || len(gnuplot) != tt.size {
+				t.Errorf("gnuplot section is %d bytes, skipped = %t, want %d bytes", len(gnuplot), skippedOK, tt.size)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+const (
+	// stageSectionPrefix is the prefix of the name of each section of the Pod logs that contains the output for a
+	// stage. The prefix is followed by the number of the stage, starting at one.
+	stageSectionPrefix = "stage-"
+)
+
+// getStageArgs will return the ab options for the given stage, that replace the Concurrency, Requests and TimeLimit
+// options for the benchmark. The time limit comes before the number of requests, as ab sets the number of requests to
+// 50000 for -t, so that the stage ends at whichever is reached first.
+func getStageArgs(stage v1a1.ApacheBenchStageSpec) string {
+	args := make([]string, 0)
+
+	if stage.Concurrency > 1 {
+		args = append(args, "-c", strconv.FormatUint(uint64(stage.Concurrency), 10))
+	}
+
+	if stage.TimeLimit > 0 {
+		args = append(args, "-t", strconv.FormatUint(uint64(stage.TimeLimit), 10))
+	}
+
+	if stage.Requests > 1 {
+		args = append(args, "-n", strconv.FormatUint(uint64(stage.Requests), 10))
+	}
+
+	return strings.Join(args, " ")
+}
+
+// getStageOutputs will return the output for each stage of the given ApacheBench from the given sections of the Pod
+// logs, in order. Nil is returned if there are no stages.
+func getStageOutputs(cr *v1a1.ApacheBench, sections map[string]string) []string {
+	if len(cr.Spec.Stages) <= 0 {
+		return nil
+	}
+
+	outputs := make([]string, len(cr.Spec.Stages))
+	for i := range cr.Spec.Stages {
+		outputs[i] = sections[getStageSection(i)]
+	}
+	return outputs
+}
+
+// getStageSection will return the name of the section of the Pod logs that contains the output for the stage with the
+// given index.
+func getStageSection(index int) string {
+	return stageSectionPrefix + strconv.Itoa(index+1)
+}
+
+// joinStageOutputs will return the given output for each stage combined, with a line that marks the start of each
+// stage, so that the output can be stored as a whole.
+func joinStageOutputs(outputs []string) string {
+	b := &strings.Builder{}
+	for i, output := range outputs {
+		fmt.Fprintf(b, "%s%s%s\n", outputSectionPrefix, getStageSection(i), outputSectionSuffix)
+		b.WriteString(output)
+	}
+	return b.String()
+}
+
+// newStageResults will return the combined results for each stage of the given ApacheBench, using the given parsed
+// results from each Pod for each stage. Nil is returned if there are no stages.
+func newStageResults(cr *v1a1.ApacheBench, summaries [][]v1a1.ApacheBenchSummary) []v1a1.ApacheBenchStageResult {
+	if len(cr.Spec.Stages) <= 0 {
+		return nil
+	}
+
+	results := make([]v1a1.ApacheBenchStageResult, 0)
+	for i, stage := range cr.Spec.Stages {
+		result := v1a1.ApacheBenchStageResult{
+			Concurrency: stage.Concurrency,
+			Number:      int32(i + 1),
+			Requests:    stage.Requests,
+			TimeLimit:   stage.TimeLimit,
+		}
+		if i < len(summaries) {
+			result.Aggregate = aggregateSummaries(summaries[i])
+		}
+		results = append(results, result)
+	}
+	return results
+}
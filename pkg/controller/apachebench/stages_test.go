@@ -0,0 +1,174 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+// testStages are the stages used by the tests, where the second stage fails when run by the stand-in ab.
+var testStages = []v1a1.ApacheBenchStageSpec{
+	{Concurrency: 10, Requests: 100},
+	{Concurrency: 50, Requests: 1000, TimeLimit: 30},
+	{Concurrency: 100, TimeLimit: 60},
+}
+
+// stubAB is a stand-in for ab that prints its arguments, and fails at a concurrency of 50.
+const stubAB = `#!/bin/sh
+echo "ab $*"
+case " $* " in
+*" -c 50 "*) exit 22 ;;
+esac
+`
+
+func TestGetStageArgs(t *testing.T) {
+	tests := []struct {
+		name  string
+		stage v1a1.ApacheBenchStageSpec
+		want  string
+	}{
+		{
+			name:  "single connection",
+			stage: v1a1.ApacheBenchStageSpec{Concurrency: 1},
+		},
+		{
+			name:  "requests",
+			stage: v1a1.ApacheBenchStageSpec{Concurrency: 10, Requests: 100},
+			want:  "-c 10 -n 100",
+		},
+		{
+			name:  "time limit",
+			stage: v1a1.ApacheBenchStageSpec{Concurrency: 10, TimeLimit: 30},
+			want:  "-c 10 -t 30",
+		},
+		{
+			name:  "requests and time limit",
+			stage: v1a1.ApacheBenchStageSpec{Concurrency: 10, Requests: 100, TimeLimit: 30},
+			want:  "-c 10 -t 30 -n 100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getStageArgs(tt.stage); got != tt.want {
+				t.Errorf("getStageArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetScriptStages(t *testing.T) {
+	cr := &v1a1.ApacheBench{Spec: v1a1.ApacheBenchSpec{Stages: testStages}}
+	script := getScript(cr, &v1a1.ApacheBenchRun{Number: 1})
+
+	for _, want := range []string{
+		`  ab -c 10 -n 100 "$@" > "$out-stage-1.txt" 2>&1`,
+		`  ab -c 50 -t 30 -n 1000 "$@" > "$out-stage-2.txt" 2>&1`,
+		`  ab -c 100 -t 60 "$@" > "$out-stage-3.txt" 2>&1`,
+		`  echo "==> apachebench:stage-2 <=="`,
+		`  cat "$out-stage-2.txt"`,
+	} {
+		if !strings.Contains(script, want+"\n") {
+			t.Errorf("getScript() does not contain %q:\n%s", want, script)
+		}
+	}
+	if !strings.HasSuffix(script, "\nexit $rc") {
+		t.Errorf("getScript() does not exit with the status of the stages:\n%s", script)
+	}
+
+	// The script is run with a stand-in ab, when a shell is available.
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh is not available")
+	}
+	dir, err := ioutil.TempDir("/tmp", "stages")
+	if err != nil {
+		t.Skipf("unable to create a directory in /tmp: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "ab"), []byte(stubAB), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(sh, append([]string{"-c", script}, "ab", "-k", "http://example.com/")...)
+	cmd.Env = append(os.Environ(), "PATH="+dir+":"+os.Getenv("PATH"), "HOSTNAME="+filepath.Base(dir)+"/pod")
+	out, err := cmd.Output()
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok || exitErr.ExitCode() != 22 {
+		t.Fatalf("script exited with %v, want the exit status of the failed stage\n%s", err, out)
+	}
+
+	// The stages stop at the failed stage, and the output for each stage that was run is in its own section.
+	_, sections := splitOutputSections(string(out))
+	outputs := getStageOutputs(cr, sections)
+	want := []string{
+		"ab -c 10 -n 100 -k http://example.com/\n",
+		"ab -c 50 -t 30 -n 1000 -k http://example.com/\n",
+		"",
+	}
+	for i := range want {
+		if outputs[i] != want[i] {
+			t.Errorf("output for stage %d = %q, want %q", i+1, outputs[i], want[i])
+		}
+	}
+}
+
+func TestNewStageResults(t *testing.T) {
+	cr := &v1a1.ApacheBench{Spec: v1a1.ApacheBenchSpec{Stages: testStages}}
+	summaries := [][]v1a1.ApacheBenchSummary{
+		{
+			{CompleteRequests: 100, RequestsPerSecond: 200, TimePerRequest: 10},
+			{CompleteRequests: 300, RequestsPerSecond: 100, TimePerRequest: 30},
+		},
+		{
+			{CompleteRequests: 1000, RequestsPerSecond: 50, TimePerRequest: 40},
+		},
+	}
+
+	results := newStageResults(cr, summaries)
+	if len(results) != len(testStages) {
+		t.Fatalf("newStageResults() returned %d results, want %d", len(results), len(testStages))
+	}
+	for i, result := range results {
+		stage := testStages[i]
+		if result.Number != int32(i+1) || result.Concurrency != stage.Concurrency || result.Requests != stage.Requests ||
+			result.TimeLimit != stage.TimeLimit {
+			t.Errorf("result %d = %+v, want the options for stage %+v", i, result, stage)
+		}
+	}
+
+	// The results for each stage are combined across the Pods, and a stage that did not run has no results.
+	if agg := results[0].Aggregate; agg == nil || agg.CompleteRequests != 400 || !floatsEqual(agg.RequestsPerSecond, 300) ||
+		!floatsEqual(agg.TimePerRequest, 25) {
+		t.Errorf("aggregate for stage 1 = %+v, want the combined results of both Pods", agg)
+	}
+	if agg := results[1].Aggregate; agg == nil || agg.CompleteRequests != 1000 {
+		t.Errorf("aggregate for stage 2 = %+v, want the results of the Pod", agg)
+	}
+	if agg := results[2].Aggregate; agg != nil {
+		t.Errorf("aggregate for stage 3 = %+v, want nil", agg)
+	}
+
+	if got := newStageResults(&v1a1.ApacheBench{}, summaries); got != nil {
+		t.Errorf("newStageResults() = %+v without stages, want nil", got)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	// targetKindIngress is the kind of a Target that references an Ingress.
+	targetKindIngress = "Ingress"
+
+	// targetKindRoute is the kind of a Target that references an OpenShift Route.
+	targetKindRoute = "Route"
+
+	// targetKindService is the kind of a Target that references a Service.
+	targetKindService = "Service"
+
+	// targetNotReadyReason is the reason on the JobCreated condition while waiting for the Target to become ready.
+	targetNotReadyReason = "TargetNotReady"
+
+	// targetRetryInterval is the time to wait before checking whether the Target is ready again.
+	targetRetryInterval = 10 * time.Second
+)
+
+// containsString will return true if the given slice contains the given value.
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// getIngressTarget will return the URL for the given Ingress Target, or the reason that the Ingress is not ready.
+func (r *ReconcileApacheBench) getIngressTarget(cr *v1a1.ApacheBench, target *v1a1.ApacheBenchTargetSpec) (string, string, error) {
+	ingress := &networkingv1beta1.Ingress{}
+	if err := r.fetchObject(cr.Namespace, target.Name, ingress); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Sprintf("waiting for ingress '%s' to be created", target.Name), nil
+		}
+		return "", "", err
+	}
+
+	// The first rule with a host is used, falling back to the address of the load balancer.
+	host := ""
+	backend := ingress.Spec.Backend
+	for _, rule := range ingress.Spec.Rules {
+		if len(rule.Host) <= 0 {
+			continue
+		}
+		host = rule.Host
+		if rule.HTTP != nil && len(rule.HTTP.Paths) > 0 {
+			backend = &rule.HTTP.Paths[0].Backend
+		}
+		break
+	}
+	if len(host) <= 0 {
+		for _, lb := range ingress.Status.LoadBalancer.Ingress {
+			if host = lb.Hostname; len(host) <= 0 {
+				host = lb.IP
+			}
+			break
+		}
+	}
+	if len(host) <= 0 {
+		return "", fmt.Sprintf("waiting for ingress '%s' to have a host or address", target.Name), nil
+	}
+
+	if backend == nil && len(ingress.Spec.Rules) > 0 && ingress.Spec.Rules[0].HTTP != nil && len(ingress.Spec.Rules[0].HTTP.Paths) > 0 {
+		backend = &ingress.Spec.Rules[0].HTTP.Paths[0].Backend
+	}
+	if backend != nil {
+		if msg, err := r.getServiceNotReadyReason(cr.Namespace, backend.ServiceName); err != nil || len(msg) > 0 {
+			return "", msg, err
+		}
+	}
+
+	scheme := target.Scheme
+	if len(scheme) <= 0 {
+		scheme = "http"
+		for _, tls := range ingress.Spec.TLS {
+			if len(tls.Hosts) <= 0 || containsString(tls.Hosts, host) {
+				scheme = "https"
+				break
+			}
+		}
+	}
+
+	return fmt.Sprintf("%s://%s%s", scheme, host, getTargetPath(target.Path, "")), "", nil
+}
+
+// getRouteTarget will return the URL for the given OpenShift Route Target, or the reason that the Route is not ready.
+func (r *ReconcileApacheBench) getRouteTarget(cr *v1a1.ApacheBench, target *v1a1.ApacheBenchTargetSpec) (string, string, error) {
+	route := &unstructured.Unstructured{}
+	route.SetGroupVersionKind(schema.GroupVersionKind{Group: "route.openshift.io", Version: "v1", Kind: "Route"})
+	if err := r.fetchObject(cr.Namespace, target.Name, route); err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", fmt.Sprintf("waiting for route '%s' to be created", target.Name), nil
+		}
+		return "", "", err
+	}
+
+	host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+	if len(host) <= 0 {
+		ingresses, _, _ := unstructured.NestedSlice(route.Object, "status", "ingress")
+		for _, i := range ingresses {
+			if ingress, ok := i.(map[string]interface{}); ok {
+				host, _, _ = unstructured.NestedString(ingress, "host")
+				break
+			}
+		}
+	}
+	if len(host) <= 0 {
+		return "", fmt.Sprintf("waiting for route '%s' to have a host", target.Name), nil
+	}
+
+	if service, _, _ := unstructured.NestedString(route.Object, "spec", "to", "name"); len(service) > 0 {
+		if msg, err := r.getServiceNotReadyReason(cr.Namespace, service); err != nil || len(msg) > 0 {
+			return "", msg, err
+		}
+	}
+
+	scheme := target.Scheme
+	if len(scheme) <= 0 {
+		scheme = "http"
+		if _, found, _ := unstructured.NestedMap(route.Object, "spec", "tls"); found {
+			scheme = "https"
+		}
+	}
+
+	path, _, _ := unstructured.NestedString(route.Object, "spec", "path")
+	return fmt.Sprintf("%s://%s%s", scheme, host, getTargetPath(target.Path, path)), "", nil
+}
+
+// getServiceNotReadyReason will return the reason that the Service with the given name does not have any ready
+// endpoints, or an empty string if the Service is ready. An ExternalName Service is always considered ready.
+func (r *ReconcileApacheBench) getServiceNotReadyReason(namespace string, name string) (string, error) {
+	svc := &corev1.Service{}
+	if err := r.fetchObject(namespace, name, svc); err != nil {
+		if apierrors.IsNotFound(err) {
+			return fmt.Sprintf("waiting for service '%s' to be created", name), nil
+		}
+		return "", err
+	}
+
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		return "", nil
+	}
+
+	endpoints := &corev1.Endpoints{}
+	if err := r.fetchObject(namespace, name, endpoints); err != nil && !apierrors.IsNotFound(err) {
+		return "", err
+	}
+
+	for _, subset := range endpoints.Subsets {
+		if len(subset.Addresses) > 0 {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("waiting for service '%s' to have ready endpoints", name), nil
+}
+
+// getServicePort will return the port of the given Service that matches the given name or number, or the first port
+// when not specified. Nil is returned if there is no matching port.
+func getServicePort(svc *corev1.Service, port *intstr.IntOrString) *corev1.ServicePort {
+	for i, p := range svc.Spec.Ports {
+		if port == nil ||
+			(port.Type == intstr.Int && p.Port == port.IntVal) ||
+			(port.Type == intstr.String && p.Name == port.StrVal) {
+			return &svc.Spec.Ports[i]
+		}
+	}
+	return nil
+}
+
+// getServiceTarget will return the URL for the given Service Target, or the reason that the Service is not ready.
+func (r *ReconcileApacheBench) getServiceTarget(cr *v1a1.ApacheBench, target *v1a1.ApacheBenchTargetSpec) (string, string, error) {
+	if msg, err := r.getServiceNotReadyReason(cr.Namespace, target.Name); err != nil || len(msg) > 0 {
+		return "", msg, err
+	}
+
+	svc := &corev1.Service{}
+	if err := r.fetchObject(cr.Namespace, target.Name, svc); err != nil {
+		return "", "", err
+	}
+
+	port := getServicePort(svc, target.Port)
+	if port == nil {
+		if target.Port == nil {
+			return "", fmt.Sprintf("waiting for service '%s' to have a port", target.Name), nil
+		}
+		return "", fmt.Sprintf("waiting for service '%s' to have port '%s'", target.Name, target.Port.String()), nil
+	}
+
+	host := fmt.Sprintf("%s.%s.svc", svc.Name, svc.Namespace)
+	if svc.Spec.Type == corev1.ServiceTypeExternalName {
+		host = svc.Spec.ExternalName
+	}
+
+	scheme := target.Scheme
+	if len(scheme) <= 0 {
+		scheme = "http"
+		if port.Port == 443 || port.Name == "https" {
+			scheme = "https"
+		}
+	}
+
+	hostPort := net.JoinHostPort(host, strconv.Itoa(int(port.Port)))
+	return fmt.Sprintf("%s://%s%s", scheme, hostPort, getTargetPath(target.Path, "")), "", nil
+}
+
+// getTargetPath will return the given path for the URL, or the given default path when not set.
+// The returned path always starts with a slash.
+func getTargetPath(path string, defaultPath string) string {
+	if len(path) <= 0 {
+		path = defaultPath
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return path
+}
+
+// getTargetURL will return the URL to benchmark for the given ApacheBench. When a Target is set, the URL is resolved
+// from the target and the reason is returned instead if the target is not ready.
+func (r *ReconcileApacheBench) getTargetURL(cr *v1a1.ApacheBench) (string, string, error) {
+	target := cr.Spec.Target
+	if target == nil && len(cr.Spec.URL) <= 0 && len(cr.Spec.Endpoints) > 0 {
+		return "", "", nil // Each endpoint has its own URL
+	}
+
+	if (target == nil) == (len(cr.Spec.URL) <= 0) {
+		return "", "", r.failJobCreation(cr, "InvalidTarget", "exactly one of url or target must be set")
+	}
+
+	if target == nil {
+		return cr.Spec.URL, "", nil
+	}
+
+	switch target.Kind {
+	case targetKindIngress:
+		return r.getIngressTarget(cr, target)
+	case targetKindRoute:
+		return r.getRouteTarget(cr, target)
+	case targetKindService:
+		return r.getServiceTarget(cr, target)
+	}
+	return "", "", r.failJobCreation(cr, "InvalidTarget", fmt.Sprintf("unsupported target kind '%s'", target.Kind))
+}
+
+// isWaitingForTarget will return true if the current run of the given ApacheBench is waiting for the Target to
+// become ready, or the readiness checks to pass, before the Job is created.
+func isWaitingForTarget(cr *v1a1.ApacheBench) bool {
+	run := cr.GetCurrentRun()
+	if run == nil || run.Phase != v1a1.ApacheBenchPhasePending {
+		return false
+	}
+
+	cond := getCondition(cr, v1a1.ApacheBenchConditionJobCreated)
+	return cond != nil && cond.Status == corev1.ConditionFalse && cond.Reason == targetNotReadyReason
+}
@@ -0,0 +1,364 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1beta1 "k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// newTestService will return a Service named app with the given ports.
+func newTestService(ports ...corev1.ServicePort) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "benchmark"},
+		Spec:       corev1.ServiceSpec{Ports: ports},
+	}
+}
+
+// newTestEndpoints will return the Endpoints for the Service named app, with a ready address when ready is true.
+func newTestEndpoints(ready bool) *corev1.Endpoints {
+	subset := corev1.EndpointSubset{NotReadyAddresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}
+	if ready {
+		subset = corev1.EndpointSubset{Addresses: []corev1.EndpointAddress{{IP: "10.0.0.1"}}}
+	}
+	return &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "benchmark"},
+		Subsets:    []corev1.EndpointSubset{subset},
+	}
+}
+
+// newTestIngress will return an Ingress named web, with a rule for each of the given hosts that routes to the Service
+// named app, and TLS for the given hosts.
+func newTestIngress(hosts []string, tls []networkingv1beta1.IngressTLS) *networkingv1beta1.Ingress {
+	ingress := &networkingv1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "benchmark"},
+		Spec:       networkingv1beta1.IngressSpec{TLS: tls},
+	}
+	for _, host := range hosts {
+		ingress.Spec.Rules = append(ingress.Spec.Rules, networkingv1beta1.IngressRule{
+			Host: host,
+			IngressRuleValue: networkingv1beta1.IngressRuleValue{HTTP: &networkingv1beta1.HTTPIngressRuleValue{
+				Paths: []networkingv1beta1.HTTPIngressPath{{
+					Backend: networkingv1beta1.IngressBackend{ServiceName: "app", ServicePort: intstr.FromInt(80)},
+				}},
+			}},
+		})
+	}
+	return ingress
+}
+
+// newTestRoute will return an OpenShift Route named web with the given spec, that routes to the Service named app.
+func newTestRoute(spec map[string]interface{}) *unstructured.Unstructured {
+	spec["to"] = map[string]interface{}{"kind": "Service", "name": "app"}
+	route := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+	route.SetAPIVersion("route.openshift.io/v1")
+	route.SetKind("Route")
+	route.SetName("web")
+	route.SetNamespace("benchmark")
+	return route
+}
+
+func TestGetServicePort(t *testing.T) {
+	svc := newTestService(
+		corev1.ServicePort{Name: "http", Port: 8080},
+		corev1.ServicePort{Name: "https", Port: 8443},
+	)
+	named := intstr.FromString("https")
+	numbered := intstr.FromInt(8443)
+	missingName := intstr.FromString("metrics")
+	missingNumber := intstr.FromInt(9090)
+
+	tests := []struct {
+		name string
+		svc  *corev1.Service
+		port *intstr.IntOrString
+		want int32
+	}{
+		{name: "first port", svc: svc, want: 8080},
+		{name: "by name", svc: svc, port: &named, want: 8443},
+		{name: "by number", svc: svc, port: &numbered, want: 8443},
+		{name: "missing name", svc: svc, port: &missingName},
+		{name: "missing number", svc: svc, port: &missingNumber},
+		{name: "no ports", svc: newTestService()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := getServicePort(tt.svc, tt.port)
+			if tt.want == 0 {
+				if got != nil {
+					t.Errorf("getServicePort() = %+v, want nil", got)
+				}
+			} else if got == nil || got.Port != tt.want {
+				t.Errorf("getServicePort() = %+v, want port %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTargetPath(t *testing.T) {
+	tests := []struct {
+		path        string
+		defaultPath string
+		want        string
+	}{
+		{"", "", "/"},
+		{"", "/app", "/app"},
+		{"", "app", "/app"},
+		{"/index.html", "/app", "/index.html"},
+		{"index.html", "", "/index.html"},
+	}
+
+	for _, tt := range tests {
+		if got := getTargetPath(tt.path, tt.defaultPath); got != tt.want {
+			t.Errorf("getTargetPath(%q, %q) = %q, want %q", tt.path, tt.defaultPath, got, tt.want)
+		}
+	}
+}
+
+func TestGetTargetURL(t *testing.T) {
+	https := intstr.FromString("https")
+	metrics := intstr.FromString("metrics")
+	externalName := newTestService(corev1.ServicePort{Port: 80})
+	externalName.Spec.Type = corev1.ServiceTypeExternalName
+	externalName.Spec.ExternalName = "app.example.com"
+	loadBalancer := newTestIngress(nil, nil)
+	loadBalancer.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{{IP: "203.0.113.10"}}
+
+	tests := []struct {
+		name        string
+		target      v1a1.ApacheBenchTargetSpec
+		objs        []runtime.Object
+		want        string
+		wantWaiting string
+	}{
+		{
+			name:        "service not found",
+			target:      v1a1.ApacheBenchTargetSpec{Kind: targetKindService, Name: "app"},
+			wantWaiting: "waiting for service 'app' to be created",
+		},
+		{
+			name:        "service not ready",
+			target:      v1a1.ApacheBenchTargetSpec{Kind: targetKindService, Name: "app"},
+			objs:        []runtime.Object{newTestService(corev1.ServicePort{Port: 80}), newTestEndpoints(false)},
+			wantWaiting: "waiting for service 'app' to have ready endpoints",
+		},
+		{
+			name:   "service first port",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindService, Name: "app", Path: "index.html"},
+			objs: []runtime.Object{
+				newTestService(corev1.ServicePort{Name: "web", Port: 8080}, corev1.ServicePort{Name: "https", Port: 8443}),
+				newTestEndpoints(true),
+			},
+			want: "http://app.benchmark.svc:8080/index.html",
+		},
+		{
+			name:   "service https port by name",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindService, Name: "app", Port: &https},
+			objs: []runtime.Object{
+				newTestService(corev1.ServicePort{Name: "web", Port: 8080}, corev1.ServicePort{Name: "https", Port: 8443}),
+				newTestEndpoints(true),
+			},
+			want: "https://app.benchmark.svc:8443/",
+		},
+		{
+			name:   "service port 443",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindService, Name: "app"},
+			objs:   []runtime.Object{newTestService(corev1.ServicePort{Name: "web", Port: 443}), newTestEndpoints(true)},
+			want:   "https://app.benchmark.svc:443/",
+		},
+		{
+			name:   "service scheme",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindService, Name: "app", Scheme: "http"},
+			objs:   []runtime.Object{newTestService(corev1.ServicePort{Name: "web", Port: 443}), newTestEndpoints(true)},
+			want:   "http://app.benchmark.svc:443/",
+		},
+		{
+			name:        "service port not found",
+			target:      v1a1.ApacheBenchTargetSpec{Kind: targetKindService, Name: "app", Port: &metrics},
+			objs:        []runtime.Object{newTestService(corev1.ServicePort{Name: "web", Port: 80}), newTestEndpoints(true)},
+			wantWaiting: "waiting for service 'app' to have port 'metrics'",
+		},
+		{
+			name:   "external name service",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindService, Name: "app"},
+			objs:   []runtime.Object{externalName},
+			want:   "http://app.example.com:80/",
+		},
+		{
+			name:        "ingress not found",
+			target:      v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web"},
+			wantWaiting: "waiting for ingress 'web' to be created",
+		},
+		{
+			name:   "ingress rule host",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web", Path: "/search"},
+			objs: []runtime.Object{
+				newTestIngress([]string{"", "www.example.com", "api.example.com"}, nil),
+				newTestService(corev1.ServicePort{Port: 80}),
+				newTestEndpoints(true),
+			},
+			want: "http://www.example.com/search",
+		},
+		{
+			name:   "ingress tls for the host",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web"},
+			objs: []runtime.Object{
+				newTestIngress([]string{"www.example.com"}, []networkingv1beta1.IngressTLS{
+					{Hosts: []string{"api.example.com"}},
+					{Hosts: []string{"www.example.com"}},
+				}),
+				newTestService(corev1.ServicePort{Port: 80}),
+				newTestEndpoints(true),
+			},
+			want: "https://www.example.com/",
+		},
+		{
+			name:   "ingress tls for another host",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web"},
+			objs: []runtime.Object{
+				newTestIngress([]string{"www.example.com"}, []networkingv1beta1.IngressTLS{{Hosts: []string{"api.example.com"}}}),
+				newTestService(corev1.ServicePort{Port: 80}),
+				newTestEndpoints(true),
+			},
+			want: "http://www.example.com/",
+		},
+		{
+			name:   "ingress tls without hosts",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web"},
+			objs: []runtime.Object{
+				newTestIngress([]string{"www.example.com"}, []networkingv1beta1.IngressTLS{{SecretName: "tls"}}),
+				newTestService(corev1.ServicePort{Port: 80}),
+				newTestEndpoints(true),
+			},
+			want: "https://www.example.com/",
+		},
+		{
+			name:   "ingress scheme",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web", Scheme: "http"},
+			objs: []runtime.Object{
+				newTestIngress([]string{"www.example.com"}, []networkingv1beta1.IngressTLS{{SecretName: "tls"}}),
+				newTestService(corev1.ServicePort{Port: 80}),
+				newTestEndpoints(true),
+			},
+			want: "http://www.example.com/",
+		},
+		{
+			name:   "ingress load balancer address",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web"},
+			objs:   []runtime.Object{loadBalancer},
+			want:   "http://203.0.113.10/",
+		},
+		{
+			name:        "ingress without a host",
+			target:      v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web"},
+			objs:        []runtime.Object{newTestIngress(nil, nil)},
+			wantWaiting: "waiting for ingress 'web' to have a host or address",
+		},
+		{
+			name:        "ingress backend not ready",
+			target:      v1a1.ApacheBenchTargetSpec{Kind: targetKindIngress, Name: "web"},
+			objs:        []runtime.Object{newTestIngress([]string{"www.example.com"}, nil)},
+			wantWaiting: "waiting for service 'app' to be created",
+		},
+		{
+			name:   "route",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindRoute, Name: "web"},
+			objs: []runtime.Object{
+				newTestRoute(map[string]interface{}{"host": "www.example.com", "path": "/app", "tls": map[string]interface{}{}}),
+				newTestService(corev1.ServicePort{Port: 80}),
+				newTestEndpoints(true),
+			},
+			want: "https://www.example.com/app",
+		},
+		{
+			name:   "route path",
+			target: v1a1.ApacheBenchTargetSpec{Kind: targetKindRoute, Name: "web", Path: "/index.html"},
+			objs: []runtime.Object{
+				newTestRoute(map[string]interface{}{"host": "www.example.com", "path": "/app"}),
+				newTestService(corev1.ServicePort{Port: 80}),
+				newTestEndpoints(true),
+			},
+			want: "http://www.example.com/index.html",
+		},
+		{
+			name:        "route without a host",
+			target:      v1a1.ApacheBenchTargetSpec{Kind: targetKindRoute, Name: "web"},
+			objs:        []runtime.Object{newTestRoute(map[string]interface{}{})},
+			wantWaiting: "waiting for route 'web' to have a host",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1a1.ApacheBench{
+				ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"},
+				Spec:       v1a1.ApacheBenchSpec{Target: &tt.target},
+			}
+			r := newTestReconciler(t, append(tt.objs, cr)...)
+
+			got, waiting, err := r.getTargetURL(cr)
+			if err != nil {
+				t.Fatalf("getTargetURL() returned an error: %v", err)
+			}
+			if got != tt.want || waiting != tt.wantWaiting {
+				t.Errorf("getTargetURL() = %q, %q, want %q, %q", got, waiting, tt.want, tt.wantWaiting)
+			}
+		})
+	}
+}
+
+func TestGetTargetURLInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		spec v1a1.ApacheBenchSpec
+		want string
+	}{
+		{
+			name: "url and target",
+			spec: v1a1.ApacheBenchSpec{Target: &v1a1.ApacheBenchTargetSpec{Kind: targetKindService}, URL: "http://example.com/"},
+			want: "exactly one of url or target must be set",
+		},
+		{
+			name: "neither url nor target",
+			want: "exactly one of url or target must be set",
+		},
+		{
+			name: "unsupported kind",
+			spec: v1a1.ApacheBenchSpec{Target: &v1a1.ApacheBenchTargetSpec{Kind: "Gateway"}},
+			want: "unsupported target kind 'Gateway'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1a1.ApacheBench{ObjectMeta: metav1.ObjectMeta{Name: "example", Namespace: "benchmark"}, Spec: tt.spec}
+			r := newTestReconciler(t, cr)
+
+			_, _, err := r.getTargetURL(cr)
+			if err == nil || err.Error() != tt.want || !isJobCreationError(err) {
+				t.Errorf("getTargetURL() error = %v, want a job creation error %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"fmt"
+	"strings"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// evaluateThresholds will compare the given summary against the given thresholds and return a description of each
+// threshold that was breached.
+func evaluateThresholds(t *v1a1.ApacheBenchThresholdsSpec, summary *v1a1.ApacheBenchSummary) []string {
+	breaches := make([]string, 0)
+
+	if summary == nil {
+		return append(breaches, "results are not available for the run")
+	}
+
+	if t.MinRequestsPerSecond != nil && summary.RequestsPerSecond < *t.MinRequestsPerSecond {
+		breaches = append(breaches, fmt.Sprintf("requests per second %.2f is below the minimum of %.2f",
+			summary.RequestsPerSecond, *t.MinRequestsPerSecond))
+	}
+
+	if t.MaxMeanLatency != nil && summary.TimePerRequest > *t.MaxMeanLatency {
+		breaches = append(breaches, fmt.Sprintf("mean latency %.3fms is above the maximum of %.3fms",
+			summary.TimePerRequest, *t.MaxMeanLatency))
+	}
+
+	if t.MaxP95Latency != nil || t.MaxP99Latency != nil {
+		if summary.Percentiles == nil {
+			breaches = append(breaches, "latency percentiles are not available for the run")
+		} else {
+			if t.MaxP95Latency != nil && summary.Percentiles.P95 > *t.MaxP95Latency {
+				breaches = append(breaches, fmt.Sprintf("p95 latency %.3fms is above the maximum of %.3fms",
+					summary.Percentiles.P95, *t.MaxP95Latency))
+			}
+			if t.MaxP99Latency != nil && summary.Percentiles.P99 > *t.MaxP99Latency {
+				breaches = append(breaches, fmt.Sprintf("p99 latency %.3fms is above the maximum of %.3fms",
+					summary.Percentiles.P99, *t.MaxP99Latency))
+			}
+		}
+	}
+
+	if t.MaxFailedRequestRatio != nil {
+		ratio := 0.0
+		if summary.CompleteRequests > 0 {
+			ratio = float64(summary.FailedRequests) / float64(summary.CompleteRequests)
+		}
+		if ratio > *t.MaxFailedRequestRatio {
+			breaches = append(breaches, fmt.Sprintf("failed request ratio %.4f is above the maximum of %.4f",
+				ratio, *t.MaxFailedRequestRatio))
+		}
+	}
+
+	if t.MaxNon2xxResponses != nil && summary.Non2xxResponses > *t.MaxNon2xxResponses {
+		breaches = append(breaches, fmt.Sprintf("non-2xx responses %d is above the maximum of %d",
+			summary.Non2xxResponses, *t.MaxNon2xxResponses))
+	}
+
+	return breaches
+}
+
+// updateVerdict will compare the current run for the given ApacheBench against the thresholds and update the
+// verdict in the status. A failed run does not meet the thresholds.
+func updateVerdict(cr *v1a1.ApacheBench) {
+	run := cr.GetCurrentRun()
+	if cr.Spec.Thresholds == nil || run == nil {
+		cr.Status.ThresholdBreaches = nil
+		cr.Status.Verdict = ""
+		removeCondition(cr, v1a1.ApacheBenchConditionPassed)
+		return
+	}
+
+	switch run.Phase {
+	case v1a1.ApacheBenchPhaseComplete:
+		breaches := evaluateThresholds(cr.Spec.Thresholds, run.Aggregate)
+		if len(breaches) > 0 {
+			run.Verdict = v1a1.ApacheBenchVerdictFailed
+			setCondition(cr, v1a1.ApacheBenchConditionPassed, corev1.ConditionFalse, "ThresholdsBreached",
+				strings.Join(breaches, "; "))
+		} else {
+			breaches = nil
+			run.Verdict = v1a1.ApacheBenchVerdictPassed
+			setCondition(cr, v1a1.ApacheBenchConditionPassed, corev1.ConditionTrue, "ThresholdsMet",
+				"the results meet all of the thresholds")
+		}
+		cr.Status.ThresholdBreaches = breaches
+	case v1a1.ApacheBenchPhaseFailed:
+		run.Verdict = v1a1.ApacheBenchVerdictFailed
+		cr.Status.ThresholdBreaches = []string{"the run failed"}
+		setCondition(cr, v1a1.ApacheBenchConditionPassed, corev1.ConditionFalse, "RunFailed", "the run failed")
+	default:
+		run.Verdict = ""
+		cr.Status.ThresholdBreaches = nil
+		setCondition(cr, v1a1.ApacheBenchConditionPassed, corev1.ConditionUnknown, "RunInProgress",
+			fmt.Sprintf("waiting for run %d to complete", run.Number))
+	}
+
+	cr.Status.Verdict = run.Verdict
+}
@@ -0,0 +1,252 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"reflect"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// thresholdSummary is the summary used by the threshold tests.
+var thresholdSummary = &v1a1.ApacheBenchSummary{
+	CompleteRequests:  1000,
+	FailedRequests:    20,
+	Non2xxResponses:   5,
+	Percentiles:       &v1a1.ApacheBenchPercentiles{P95: 80, P99: 120},
+	RequestsPerSecond: 250,
+	TimePerRequest:    40,
+}
+
+// float64Ptr will return a pointer to the given value.
+func float64Ptr(value float64) *float64 {
+	return &value
+}
+
+// int64Ptr will return a pointer to the given value.
+func int64Ptr(value int64) *int64 {
+	return &value
+}
+
+func TestEvaluateThresholds(t *testing.T) {
+	tests := []struct {
+		name       string
+		thresholds v1a1.ApacheBenchThresholdsSpec
+		summary    *v1a1.ApacheBenchSummary
+		want       []string
+	}{
+		{
+			name:    "no thresholds",
+			summary: thresholdSummary,
+			want:    []string{},
+		},
+		{
+			name:       "nil summary",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MinRequestsPerSecond: float64Ptr(1)},
+			want:       []string{"results are not available for the run"},
+		},
+		{
+			name: "all met",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{
+				MaxFailedRequestRatio: float64Ptr(0.02),
+				MaxMeanLatency:        float64Ptr(40),
+				MaxNon2xxResponses:    int64Ptr(5),
+				MaxP95Latency:         float64Ptr(80),
+				MaxP99Latency:         float64Ptr(120),
+				MinRequestsPerSecond:  float64Ptr(250),
+			},
+			summary: thresholdSummary,
+			want:    []string{},
+		},
+		{
+			name:       "min requests per second",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MinRequestsPerSecond: float64Ptr(300)},
+			summary:    thresholdSummary,
+			want:       []string{"requests per second 250.00 is below the minimum of 300.00"},
+		},
+		{
+			name:       "max mean latency",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MaxMeanLatency: float64Ptr(30)},
+			summary:    thresholdSummary,
+			want:       []string{"mean latency 40.000ms is above the maximum of 30.000ms"},
+		},
+		{
+			name:       "max p95 latency",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MaxP95Latency: float64Ptr(50)},
+			summary:    thresholdSummary,
+			want:       []string{"p95 latency 80.000ms is above the maximum of 50.000ms"},
+		},
+		{
+			name:       "max p99 latency",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MaxP99Latency: float64Ptr(100)},
+			summary:    thresholdSummary,
+			want:       []string{"p99 latency 120.000ms is above the maximum of 100.000ms"},
+		},
+		{
+			name:       "percentiles not available",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MaxP95Latency: float64Ptr(50), MaxP99Latency: float64Ptr(100)},
+			summary:    &v1a1.ApacheBenchSummary{RequestsPerSecond: 250},
+			want:       []string{"latency percentiles are not available for the run"},
+		},
+		{
+			name:       "max failed request ratio",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MaxFailedRequestRatio: float64Ptr(0.01)},
+			summary:    thresholdSummary,
+			want:       []string{"failed request ratio 0.0200 is above the maximum of 0.0100"},
+		},
+		{
+			name:       "failed request ratio without complete requests",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MaxFailedRequestRatio: float64Ptr(0)},
+			summary:    &v1a1.ApacheBenchSummary{FailedRequests: 10},
+			want:       []string{},
+		},
+		{
+			name:       "max non-2xx responses",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{MaxNon2xxResponses: int64Ptr(0)},
+			summary:    thresholdSummary,
+			want:       []string{"non-2xx responses 5 is above the maximum of 0"},
+		},
+		{
+			name: "all breached",
+			thresholds: v1a1.ApacheBenchThresholdsSpec{
+				MaxFailedRequestRatio: float64Ptr(0.01),
+				MaxMeanLatency:        float64Ptr(30),
+				MaxNon2xxResponses:    int64Ptr(0),
+				MaxP95Latency:         float64Ptr(50),
+				MaxP99Latency:         float64Ptr(100),
+				MinRequestsPerSecond:  float64Ptr(300),
+			},
+			summary: thresholdSummary,
+			want: []string{
+				"requests per second 250.00 is below the minimum of 300.00",
+				"mean latency 40.000ms is above the maximum of 30.000ms",
+				"p95 latency 80.000ms is above the maximum of 50.000ms",
+				"p99 latency 120.000ms is above the maximum of 100.000ms",
+				"failed request ratio 0.0200 is above the maximum of 0.0100",
+				"non-2xx responses 5 is above the maximum of 0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluateThresholds(&tt.thresholds, tt.summary); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evaluateThresholds() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateVerdict(t *testing.T) {
+	met := &v1a1.ApacheBenchThresholdsSpec{MinRequestsPerSecond: float64Ptr(200)}
+	breached := &v1a1.ApacheBenchThresholdsSpec{MinRequestsPerSecond: float64Ptr(300)}
+
+	tests := []struct {
+		name         string
+		thresholds   *v1a1.ApacheBenchThresholdsSpec
+		run          *v1a1.ApacheBenchRun
+		wantVerdict  string
+		wantBreaches []string
+		wantStatus   corev1.ConditionStatus
+		wantReason   string
+	}{
+		{
+			name: "no thresholds",
+			run:  &v1a1.ApacheBenchRun{Number: 1, Phase: v1a1.ApacheBenchPhaseComplete, Aggregate: thresholdSummary},
+		},
+		{
+			name:       "no runs",
+			thresholds: met,
+		},
+		{
+			name:        "met",
+			thresholds:  met,
+			run:         &v1a1.ApacheBenchRun{Number: 1, Phase: v1a1.ApacheBenchPhaseComplete, Aggregate: thresholdSummary},
+			wantVerdict: v1a1.ApacheBenchVerdictPassed,
+			wantStatus:  corev1.ConditionTrue,
+			wantReason:  "ThresholdsMet",
+		},
+		{
+			name:         "breached",
+			thresholds:   breached,
+			run:          &v1a1.ApacheBenchRun{Number: 1, Phase: v1a1.ApacheBenchPhaseComplete, Aggregate: thresholdSummary},
+			wantVerdict:  v1a1.ApacheBenchVerdictFailed,
+			wantBreaches: []string{"requests per second 250.00 is below the minimum of 300.00"},
+			wantStatus:   corev1.ConditionFalse,
+			wantReason:   "ThresholdsBreached",
+		},
+		{
+			name:         "complete without results",
+			thresholds:   met,
+			run:          &v1a1.ApacheBenchRun{Number: 1, Phase: v1a1.ApacheBenchPhaseComplete},
+			wantVerdict:  v1a1.ApacheBenchVerdictFailed,
+			wantBreaches: []string{"results are not available for the run"},
+			wantStatus:   corev1.ConditionFalse,
+			wantReason:   "ThresholdsBreached",
+		},
+		{
+			name:         "failed",
+			thresholds:   met,
+			run:          &v1a1.ApacheBenchRun{Number: 1, Phase: v1a1.ApacheBenchPhaseFailed, Aggregate: thresholdSummary},
+			wantVerdict:  v1a1.ApacheBenchVerdictFailed,
+			wantBreaches: []string{"the run failed"},
+			wantStatus:   corev1.ConditionFalse,
+			wantReason:   "RunFailed",
+		},
+		{
+			name:       "running",
+			thresholds: met,
+			run:        &v1a1.ApacheBenchRun{Number: 1, Phase: v1a1.ApacheBenchPhaseRunning},
+			wantStatus: corev1.ConditionUnknown,
+			wantReason: "RunInProgress",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The status starts with the verdict of a previous evaluation, which is replaced.
+			cr := &v1a1.ApacheBench{Spec: v1a1.ApacheBenchSpec{Thresholds: tt.thresholds}}
+			cr.Status.ThresholdBreaches = []string{"stale"}
+			cr.Status.Verdict = v1a1.ApacheBenchVerdictFailed
+			setCondition(cr, v1a1.ApacheBenchConditionPassed, corev1.ConditionFalse, "ThresholdsBreached", "stale")
+			if tt.run != nil {
+				cr.Status.Runs = []v1a1.ApacheBenchRun{*tt.run}
+			}
+
+			updateVerdict(cr)
+			if cr.Status.Verdict != tt.wantVerdict {
+				t.Errorf("verdict = %q, want %q", cr.Status.Verdict, tt.wantVerdict)
+			}
+			if run := cr.GetCurrentRun(); run != nil && run.Verdict != tt.wantVerdict {
+				t.Errorf("run verdict = %q, want %q", run.Verdict, tt.wantVerdict)
+			}
+			if !reflect.DeepEqual(cr.Status.ThresholdBreaches, tt.wantBreaches) {
+				t.Errorf("breaches = %q, want %q", cr.Status.ThresholdBreaches, tt.wantBreaches)
+			}
+
+			cond := getCondition(cr, v1a1.ApacheBenchConditionPassed)
+			if len(tt.wantReason) == 0 {
+				if cond != nil {
+					t.Errorf("condition = %+v, want none", cond)
+				}
+			} else if cond == nil || cond.Status != tt.wantStatus || cond.Reason != tt.wantReason {
+				t.Errorf("condition = %+v, want %s with reason %s", cond, tt.wantStatus, tt.wantReason)
+			}
+		})
+	}
+}
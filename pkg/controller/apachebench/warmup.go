@@ -0,0 +1,98 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// warmupContainerName is the name of the init container that runs the warm-up before the benchmark.
+	warmupContainerName = "warmup"
+)
+
+// getWarmup will return a copy of the given ApacheBench that runs the warm-up instead of the benchmark for the given
+// run. Only the output is produced, and socket errors do not stop the warm-up. The concurrency is limited to the
+// number of warm-up requests, as for ab.
+func getWarmup(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) *v1a1.ApacheBench {
+	warmup := cr.DeepCopy()
+	warmup.Spec.Concurrency, _ = getLoad(cr, run)
+	warmup.Spec.CSV = false
+	warmup.Spec.DisableSocketExit = true
+	warmup.Spec.Gnuplot = false
+	warmup.Spec.HTML = v1a1.ApacheBenchHTMLSpec{}
+	warmup.Spec.Mode = v1a1.ApacheBenchModeBenchmark
+	warmup.Spec.Output = nil
+	warmup.Spec.Requests = cr.Spec.Warmup.Requests
+	warmup.Spec.Stages = nil
+	warmup.Spec.TimeLimit = cr.Spec.Warmup.TimeLimit
+	warmup.Spec.Verbosity = 0
+
+	if cr.Spec.Warmup.Concurrency > 0 {
+		warmup.Spec.Concurrency = cr.Spec.Warmup.Concurrency
+	}
+
+	// ab does not allow more concurrent requests than requests in total, which is one when not set. The number of
+	// requests is not limited when there is a time limit.
+	if warmup.Spec.TimeLimit <= 0 && warmup.Spec.Concurrency > getWarmupRequests(warmup) {
+		warmup.Spec.Concurrency = getWarmupRequests(warmup)
+	}
+
+	return warmup
+}
+
+// getWarmupRequests will return the number of requests that ab performs for the given warm-up.
+func getWarmupRequests(warmup *v1a1.ApacheBench) uint32 {
+	if warmup.Spec.Requests <= 0 {
+		return 1
+	}
+	return warmup.Spec.Requests
+}
+
+// isWarmingUp will return true if the warm-up container in the given Pod is running.
+func isWarmingUp(pod corev1.Pod) bool {
+	for _, cs := range pod.Status.InitContainerStatuses {
+		if cs.Name == warmupContainerName && cs.State.Running != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// newWarmupContainer returns a new Container that runs the warm-up for the given run of the given ApacheBench.
+// The container runs as an init container, so that its output is not included in the results.
+func (r *ReconcileApacheBench) newWarmupContainer(cr *v1a1.ApacheBench, run *v1a1.ApacheBenchRun) (*corev1.Container, error) {
+	warmup := getWarmup(cr, run)
+
+	cmd, err := r.getCommand(warmup, run)
+	if err != nil {
+		return nil, err
+	}
+
+	env := make([]corev1.EnvVar, 0)
+	env = append(env, getCredentialsEnv(warmup)...)
+	env = append(env, getReferenceEnv(warmup)...)
+
+	return &corev1.Container{
+		Command:         getContainerCommand(warmup, run, cmd),
+		Env:             env,
+		Image:           getImage(warmup),
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Name:            warmupContainerName,
+		VolumeMounts:    getVolumeMounts(warmup),
+	}, nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebench
+
+import (
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+)
+
+func TestGetWarmupConcurrency(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency uint32
+		warmup      v1a1.ApacheBenchWarmupSpec
+		want        uint32
+	}{
+		{name: "benchmark concurrency", concurrency: 10, warmup: v1a1.ApacheBenchWarmupSpec{Requests: 100}, want: 10},
+		{name: "warm-up concurrency", concurrency: 10, warmup: v1a1.ApacheBenchWarmupSpec{Concurrency: 5, Requests: 100}, want: 5},
+		{name: "limited to requests", concurrency: 50, warmup: v1a1.ApacheBenchWarmupSpec{Requests: 20}, want: 20},
+		{name: "limited to one request", concurrency: 50, warmup: v1a1.ApacheBenchWarmupSpec{}, want: 1},
+		{name: "time limit", concurrency: 50, warmup: v1a1.ApacheBenchWarmupSpec{Requests: 20, TimeLimit: 10}, want: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			warmup := tt.warmup
+			cr := &v1a1.ApacheBench{}
+			cr.Spec.Concurrency = tt.concurrency
+			cr.Spec.Requests = 1000
+			cr.Spec.Warmup = &warmup
+
+			if got := getWarmup(cr, &v1a1.ApacheBenchRun{}).Spec.Concurrency; got != tt.want {
+				t.Errorf("getWarmup() concurrency = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebenchsuite
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// apacheBenchSuiteLabel is the label on each ApacheBench in a suite that contains the name of the ApacheBenchSuite.
+	apacheBenchSuiteLabel = "httpd.apache.org/apachebenchsuite"
+
+	// defaultMaxParallel is the number of benchmarks in a suite that run at once when not specified in the CR.
+	defaultMaxParallel = 1
+)
+
+// getMaxParallel will return the maximum number of benchmarks in the given ApacheBenchSuite that run at once.
+func getMaxParallel(suite *v1a1.ApacheBenchSuite) int {
+	if suite.Spec.MaxParallel == nil || *suite.Spec.MaxParallel <= 0 {
+		return defaultMaxParallel
+	}
+	return int(*suite.Spec.MaxParallel)
+}
+
+// getSuiteRunID will return the run ID for each ApacheBench in the given ApacheBenchSuite. The run ID changes with the
+// generation of the suite, so that every benchmark is run again when the suite spec changes, and with the run ID
+// annotation on the suite, so that every benchmark can be run again on demand.
+func getSuiteRunID(suite *v1a1.ApacheBenchSuite) string {
+	runID := strconv.FormatInt(suite.Generation, 10)
+	if id := suite.Annotations[v1a1.ApacheBenchRunIDAnnotation]; len(id) > 0 {
+		runID += "-" + id
+	}
+	return runID
+}
+
+// isBenchmarkFinished will return true if the run of the given ApacheBench for the given run ID has either completed
+// or failed.
+func isBenchmarkFinished(ab *v1a1.ApacheBench, runID string) bool {
+	run := ab.GetCurrentRun()
+	if run == nil || run.RunID != runID {
+		return false
+	}
+	return run.Phase == v1a1.ApacheBenchPhaseComplete || run.Phase == v1a1.ApacheBenchPhaseFailed
+}
+
+// isBenchmarkRunning will return true if the given existing ApacheBench has not finished, and so counts toward the
+// maximum number of benchmarks that run at once. An up to date ApacheBench is running until its run for the given run
+// ID finishes, while an out of date ApacheBench is running until its current run finishes, as that run still puts
+// load on the application.
+func isBenchmarkRunning(existing *v1a1.ApacheBench, desired *v1a1.ApacheBench, runID string) bool {
+	if isBenchmarkUpToDate(existing, desired) {
+		return !isBenchmarkFinished(existing, runID)
+	}
+	run := existing.GetCurrentRun()
+	return run != nil && run.Phase != v1a1.ApacheBenchPhaseComplete && run.Phase != v1a1.ApacheBenchPhaseFailed
+}
+
+// isBenchmarkUpToDate will return true if the given existing ApacheBench matches the given desired ApacheBench.
+func isBenchmarkUpToDate(existing *v1a1.ApacheBench, desired *v1a1.ApacheBench) bool {
+	return existing.Annotations[v1a1.ApacheBenchRunIDAnnotation] == desired.Annotations[v1a1.ApacheBenchRunIDAnnotation] &&
+		existing.Labels[apacheBenchSuiteLabel] == desired.Labels[apacheBenchSuiteLabel] &&
+		equality.Semantic.DeepEqual(existing.Spec, desired.Spec)
+}
+
+// newBenchmarks will return an ApacheBench for every combination of the values in the matrix of the given
+// ApacheBenchSuite, in order of URL, concurrency and keep-alive. Each ApacheBench is named after the suite, followed
+// by its position in the suite starting at one.
+func newBenchmarks(suite *v1a1.ApacheBenchSuite) []*v1a1.ApacheBench {
+	template := suite.Spec.Template
+
+	urls := suite.Spec.Matrix.URLs
+	if len(urls) <= 0 {
+		urls = []string{template.URL}
+	}
+
+	concurrency := suite.Spec.Matrix.Concurrency
+	if len(concurrency) <= 0 {
+		concurrency = []uint32{template.Concurrency}
+	}
+
+	keepAlive := suite.Spec.Matrix.KeepAlive
+	if len(keepAlive) <= 0 {
+		keepAlive = []bool{template.KeepAlive}
+	}
+
+	benchmarks := make([]*v1a1.ApacheBench, 0)
+	for _, url := range urls {
+		for _, c := range concurrency {
+			for _, ka := range keepAlive {
+				ab := newBenchmark(suite, len(benchmarks)+1)
+				if len(suite.Spec.Matrix.URLs) > 0 {
+					ab.Spec.Target = nil // The URL from the matrix replaces the target from the template.
+				}
+				ab.Spec.URL = url
+				ab.Spec.Concurrency = c
+				ab.Spec.KeepAlive = ka
+				benchmarks = append(benchmarks, ab)
+			}
+		}
+	}
+	return benchmarks
+}
+
+// newBenchmark will return a new ApacheBench with the given position in the given ApacheBenchSuite, using the spec
+// from the template of the suite.
+func newBenchmark(suite *v1a1.ApacheBenchSuite, number int) *v1a1.ApacheBench {
+	labels := make(map[string]string)
+	for key, val := range suite.Labels {
+		labels[key] = val
+	}
+	labels[apacheBenchSuiteLabel] = suite.Name
+
+	return &v1a1.ApacheBench{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", suite.Name, number),
+			Namespace: suite.Namespace,
+			Labels:    labels,
+			Annotations: map[string]string{
+				v1a1.ApacheBenchRunIDAnnotation: getSuiteRunID(suite),
+			},
+		},
+		Spec: *suite.Spec.Template.DeepCopy(),
+	}
+}
+
+// newBenchmarkStatus will return the state of the given desired ApacheBench in the suite, using the given existing
+// ApacheBench when it is up to date. The benchmark is running until the existing ApacheBench has finished the run for
+// the given run ID.
+func newBenchmarkStatus(desired *v1a1.ApacheBench, existing *v1a1.ApacheBench, runID string) v1a1.ApacheBenchSuiteBenchmarkStatus {
+	status := v1a1.ApacheBenchSuiteBenchmarkStatus{
+		Concurrency: desired.Spec.Concurrency,
+		KeepAlive:   desired.Spec.KeepAlive,
+		Name:        desired.Name,
+		Phase:       v1a1.ApacheBenchPhasePending,
+		URL:         desired.Spec.URL,
+	}
+
+	if existing == nil {
+		return status
+	}
+
+	run := existing.GetCurrentRun()
+	if run == nil || run.RunID != runID {
+		status.Phase = v1a1.ApacheBenchPhaseRunning
+		return status
+	}
+
+	status.Phase = run.Phase
+	status.Verdict = run.Verdict
+	if len(run.URL) > 0 {
+		status.URL = run.URL
+	}
+	if run.Phase == v1a1.ApacheBenchPhaseComplete {
+		status.Aggregate = run.Aggregate
+		status.ThresholdBreaches = existing.Status.ThresholdBreaches
+	}
+	return status
+}
+
+// updateSuiteStatus will set the totals, phase and verdict for the given ApacheBenchSuite from the state of each of
+// its benchmarks, and the given number of benchmarks that have been started but not finished.
+func updateSuiteStatus(suite *v1a1.ApacheBenchSuite, running int) {
+	suite.Status.ObservedGeneration = suite.Generation
+	suite.Status.Total = int32(len(suite.Status.Benchmarks))
+	suite.Status.Completed, suite.Status.Failed, suite.Status.Running = 0, 0, int32(running)
+
+	passed := true
+	for _, b := range suite.Status.Benchmarks {
+		switch b.Phase {
+		case v1a1.ApacheBenchPhaseComplete:
+			suite.Status.Completed++
+		case v1a1.ApacheBenchPhaseFailed:
+			suite.Status.Completed++
+			suite.Status.Failed++
+			passed = false
+		}
+		if b.Verdict == v1a1.ApacheBenchVerdictFailed {
+			passed = false
+		}
+	}
+
+	suite.Status.Verdict = ""
+	switch {
+	case suite.Status.Total > 0 && suite.Status.Completed >= suite.Status.Total:
+		suite.Status.Phase = v1a1.ApacheBenchPhaseComplete
+		if suite.Status.Failed > 0 {
+			suite.Status.Phase = v1a1.ApacheBenchPhaseFailed
+		}
+		suite.Status.Verdict = v1a1.ApacheBenchVerdictPassed
+		if !passed {
+			suite.Status.Verdict = v1a1.ApacheBenchVerdictFailed
+		}
+	case suite.Status.Completed > 0 || suite.Status.Running > 0:
+		suite.Status.Phase = v1a1.ApacheBenchPhaseRunning
+	default:
+		suite.Status.Phase = v1a1.ApacheBenchPhasePending
+	}
+}
+
+// deleteStaleBenchmarks will delete each of the given existing ApacheBench instances owned by the given ApacheBenchSuite
+// that are no longer in the matrix.
+func (r *ReconcileApacheBenchSuite) deleteStaleBenchmarks(suite *v1a1.ApacheBenchSuite, existing map[string]*v1a1.ApacheBench, desired []*v1a1.ApacheBench) error {
+	names := make(map[string]bool)
+	for _, ab := range desired {
+		names[ab.Name] = true
+	}
+
+	for name, ab := range existing {
+		if names[name] || !metav1.IsControlledBy(ab, suite) {
+			continue
+		}
+
+		log.Info("deleting stale apachebench", "namespace", ab.Namespace, "name", ab.Name)
+		if err := r.client.Delete(context.TODO(), ab); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		delete(existing, name)
+	}
+	return nil
+}
+
+// fetchBenchmarks will return each existing ApacheBench in the given ApacheBenchSuite, by name.
+func (r *ReconcileApacheBenchSuite) fetchBenchmarks(suite *v1a1.ApacheBenchSuite) (map[string]*v1a1.ApacheBench, error) {
+	list := &v1a1.ApacheBenchList{}
+	opts := []client.ListOption{
+		client.InNamespace(suite.Namespace),
+		client.MatchingLabels{apacheBenchSuiteLabel: suite.Name},
+	}
+
+	if err := r.client.List(context.TODO(), list, opts...); err != nil {
+		return nil, err
+	}
+
+	benchmarks := make(map[string]*v1a1.ApacheBench)
+	for i := range list.Items {
+		benchmarks[list.Items[i].Name] = &list.Items[i]
+	}
+	return benchmarks, nil
+}
+
+// reconcileBenchmarks will create or update each ApacheBench in the given ApacheBenchSuite, in order, without running
+// more than the maximum number at once, and then roll the state of each benchmark up into the status of the suite.
+func (r *ReconcileApacheBenchSuite) reconcileBenchmarks(suite *v1a1.ApacheBenchSuite) error {
+	existing, err := r.fetchBenchmarks(suite)
+	if err != nil {
+		return err
+	}
+
+	desired := newBenchmarks(suite)
+	if err := r.deleteStaleBenchmarks(suite, existing, desired); err != nil {
+		return err
+	}
+
+	runID := getSuiteRunID(suite)
+
+	// Every benchmark that has not finished is still running, including those with a run for a previous spec.
+	running := 0
+	for _, ab := range desired {
+		if cur, ok := existing[ab.Name]; ok && isBenchmarkRunning(cur, ab, runID) {
+			running++
+		}
+	}
+
+	status := suite.Status.DeepCopy()
+	suite.Status.Benchmarks = make([]v1a1.ApacheBenchSuiteBenchmarkStatus, 0)
+	suite.Status.Errors = nil
+
+	var reconcileErr error
+	for _, ab := range desired {
+		cur := existing[ab.Name]
+		if cur != nil && isBenchmarkUpToDate(cur, ab) {
+			suite.Status.Benchmarks = append(suite.Status.Benchmarks, newBenchmarkStatus(ab, cur, runID))
+			continue
+		}
+
+		// A benchmark with a run for a previous spec that has not finished is already counted as running, as the Job
+		// for that run is not stopped when the benchmark is started again.
+		if running >= getMaxParallel(suite) {
+			// Wait for a running benchmark to finish.
+			suite.Status.Benchmarks = append(suite.Status.Benchmarks, newBenchmarkStatus(ab, nil, runID))
+			continue
+		}
+
+		if err := r.startBenchmark(suite, ab, cur); err != nil {
+			suite.Status.AddError(fmt.Sprintf("unable to start apachebench '%s': %v", ab.Name, err))
+			suite.Status.Benchmarks = append(suite.Status.Benchmarks, newBenchmarkStatus(ab, nil, runID))
+			reconcileErr = err
+			continue
+		}
+		suite.Status.Benchmarks = append(suite.Status.Benchmarks, newBenchmarkStatus(ab, ab, runID))
+		running++
+	}
+
+	updateSuiteStatus(suite, running)
+	if !equality.Semantic.DeepEqual(status, &suite.Status) {
+		if err := r.client.Status().Update(context.TODO(), suite); err != nil {
+			return err
+		}
+	}
+
+	return reconcileErr
+}
+
+// startBenchmark will create the given desired ApacheBench for the given ApacheBenchSuite, or update the given
+// existing ApacheBench to match it, which starts a new run.
+func (r *ReconcileApacheBenchSuite) startBenchmark(suite *v1a1.ApacheBenchSuite, desired *v1a1.ApacheBench, existing *v1a1.ApacheBench) error {
+	if existing == nil {
+		if err := controllerutil.SetControllerReference(suite, desired, r.scheme); err != nil {
+			return err
+		}
+
+		log.Info("creating apachebench", "namespace", desired.Namespace, "name", desired.Name)
+		return r.client.Create(context.TODO(), desired)
+	}
+
+	if !metav1.IsControlledBy(existing, suite) {
+		return fmt.Errorf("apachebench '%s' is not owned by the suite", existing.Name)
+	}
+
+	if existing.Annotations == nil {
+		existing.Annotations = make(map[string]string)
+	}
+	existing.Annotations[v1a1.ApacheBenchRunIDAnnotation] = desired.Annotations[v1a1.ApacheBenchRunIDAnnotation]
+	existing.Labels = desired.Labels
+	existing.Spec = desired.Spec
+
+	log.Info("updating apachebench", "namespace", existing.Namespace, "name", existing.Name)
+	return r.client.Update(context.TODO(), existing)
+}
@@ -0,0 +1,282 @@
+// Copyright 2020 Apache Bench Operator Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// 	 http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apachebenchsuite
+
+import (
+	"context"
+	"testing"
+
+	v1a1 "github.com/jmckind/apache-bench-operator/pkg/apis/httpd/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// newTestReconciler will return a ReconcileApacheBenchSuite that uses a fake client containing the given objects.
+func newTestReconciler(t *testing.T, objs ...runtime.Object) *ReconcileApacheBenchSuite {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1a1.SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return &ReconcileApacheBenchSuite{client: fake.NewFakeClientWithScheme(s, objs...), scheme: s}
+}
+
+func TestUpdateSuiteStatus(t *testing.T) {
+	tests := []struct {
+		name        string
+		benchmarks  []v1a1.ApacheBenchSuiteBenchmarkStatus
+		running     int
+		wantPhase   string
+		wantVerdict string
+		wantFailed  int32
+	}{
+		{
+			name:      "no benchmarks",
+			wantPhase: v1a1.ApacheBenchPhasePending,
+		},
+		{
+			name: "waiting",
+			benchmarks: []v1a1.ApacheBenchSuiteBenchmarkStatus{
+				{Phase: v1a1.ApacheBenchPhasePending},
+			},
+			wantPhase: v1a1.ApacheBenchPhasePending,
+		},
+		{
+			name: "partly complete",
+			benchmarks: []v1a1.ApacheBenchSuiteBenchmarkStatus{
+				{Phase: v1a1.ApacheBenchPhaseComplete, Verdict: v1a1.ApacheBenchVerdictPassed},
+				{Phase: v1a1.ApacheBenchPhasePending},
+			},
+			wantPhase: v1a1.ApacheBenchPhaseRunning,
+		},
+		{
+			name: "all passed",
+			benchmarks: []v1a1.ApacheBenchSuiteBenchmarkStatus{
+				{Phase: v1a1.ApacheBenchPhaseComplete, Verdict: v1a1.ApacheBenchVerdictPassed},
+				{Phase: v1a1.ApacheBenchPhaseComplete},
+			},
+			wantPhase:   v1a1.ApacheBenchPhaseComplete,
+			wantVerdict: v1a1.ApacheBenchVerdictPassed,
+		},
+		{
+			name: "threshold breached",
+			benchmarks: []v1a1.ApacheBenchSuiteBenchmarkStatus{
+				{Phase: v1a1.ApacheBenchPhaseComplete, Verdict: v1a1.ApacheBenchVerdictPassed},
+				{Phase: v1a1.ApacheBenchPhaseComplete, Verdict: v1a1.ApacheBenchVerdictFailed},
+			},
+			wantPhase:   v1a1.ApacheBenchPhaseComplete,
+			wantVerdict: v1a1.ApacheBenchVerdictFailed,
+		},
+		{
+			name: "benchmark failed",
+			benchmarks: []v1a1.ApacheBenchSuiteBenchmarkStatus{
+				{Phase: v1a1.ApacheBenchPhaseComplete, Verdict: v1a1.ApacheBenchVerdictPassed},
+				{Phase: v1a1.ApacheBenchPhaseFailed},
+			},
+			wantPhase:   v1a1.ApacheBenchPhaseFailed,
+			wantVerdict: v1a1.ApacheBenchVerdictFailed,
+			wantFailed:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suite := &v1a1.ApacheBenchSuite{}
+			suite.Status.Benchmarks = tt.benchmarks
+
+			updateSuiteStatus(suite, tt.running)
+			if suite.Status.Phase != tt.wantPhase || suite.Status.Verdict != tt.wantVerdict ||
+				suite.Status.Failed != tt.wantFailed {
+				t.Errorf("updateSuiteStatus() phase = %q, verdict = %q, failed = %d, want %q, %q, %d",
+					suite.Status.Phase, suite.Status.Verdict, suite.Status.Failed,
+					tt.wantPhase, tt.wantVerdict, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestReconcileSuiteStatus(t *testing.T) {
+	maxParallel := int32(2)
+	suite := &v1a1.ApacheBenchSuite{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "benchmark", Generation: 1},
+		Spec: v1a1.ApacheBenchSuiteSpec{
+			Matrix:      v1a1.ApacheBenchSuiteMatrixSpec{Concurrency: []uint32{1, 10, 50}},
+			MaxParallel: &maxParallel,
+			Template:    v1a1.ApacheBenchSpec{Requests: 100, URL: "http://example.com/"},
+		},
+	}
+	r := newTestReconciler(t, suite)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: suite.Namespace, Name: suite.Name}}
+
+	// reconcileStatus will reconcile the suite and return its status.
+	reconcileStatus := func() v1a1.ApacheBenchSuiteStatus {
+		t.Helper()
+		if _, err := r.Reconcile(req); err != nil {
+			t.Fatalf("Reconcile returned an error: %v", err)
+		}
+		got := &v1a1.ApacheBenchSuite{}
+		if err := r.client.Get(context.TODO(), req.NamespacedName, got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Status
+	}
+
+	// finish will record a finished run for the suite on the ApacheBench with the given name.
+	finish := func(name string, run v1a1.ApacheBenchRun) {
+		t.Helper()
+		ab := &v1a1.ApacheBench{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: suite.Namespace, Name: name}, ab); err != nil {
+			t.Fatal(err)
+		}
+		run.Number = 1
+		run.RunID = ab.Annotations[v1a1.ApacheBenchRunIDAnnotation]
+		ab.Status.Runs = []v1a1.ApacheBenchRun{run}
+		if err := r.client.Status().Update(context.TODO(), ab); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the maximum number of benchmarks are started at once.
+	status := reconcileStatus()
+	if status.Total != 3 || status.Running != 2 || status.Completed != 0 || status.Phase != v1a1.ApacheBenchPhaseRunning {
+		t.Fatalf("unexpected status after the first reconcile: %+v", status)
+	}
+	if status.Benchmarks[2].Phase != v1a1.ApacheBenchPhasePending {
+		t.Errorf("expected the third benchmark to wait, got %+v", status.Benchmarks[2])
+	}
+
+	// The results of the finished benchmarks are added to the suite, and the next benchmark is started.
+	finish("web-1", v1a1.ApacheBenchRun{
+		Aggregate: &v1a1.ApacheBenchSummary{RequestsPerSecond: 120},
+		Phase:     v1a1.ApacheBenchPhaseComplete,
+		URL:       "http://example.com/",
+		Verdict:   v1a1.ApacheBenchVerdictPassed,
+	})
+	finish("web-2", v1a1.ApacheBenchRun{
+		Aggregate: &v1a1.ApacheBenchSummary{RequestsPerSecond: 80},
+		Phase:     v1a1.ApacheBenchPhaseComplete,
+		Verdict:   v1a1.ApacheBenchVerdictPassed,
+	})
+	status = reconcileStatus()
+	if status.Completed != 2 || status.Running != 1 || status.Phase != v1a1.ApacheBenchPhaseRunning || len(status.Verdict) > 0 {
+		t.Fatalf("unexpected status after two benchmarks finished: %+v", status)
+	}
+	if b := status.Benchmarks[0]; b.Concurrency != 1 || b.Aggregate == nil || b.Aggregate.RequestsPerSecond != 120 ||
+		b.Verdict != v1a1.ApacheBenchVerdictPassed {
+		t.Errorf("expected the results of the first benchmark, got %+v", b)
+	}
+
+	// The suite fails once every benchmark has finished, as one of them failed.
+	finish("web-3", v1a1.ApacheBenchRun{Phase: v1a1.ApacheBenchPhaseFailed})
+	status = reconcileStatus()
+	if status.Completed != 3 || status.Failed != 1 || status.Running != 0 ||
+		status.Phase != v1a1.ApacheBenchPhaseFailed || status.Verdict != v1a1.ApacheBenchVerdictFailed {
+		t.Fatalf("unexpected status after every benchmark finished: %+v", status)
+	}
+	if b := status.Benchmarks[2]; b.Concurrency != 50 || b.Aggregate != nil || b.Phase != v1a1.ApacheBenchPhaseFailed {
+		t.Errorf("expected the third benchmark to have failed without results, got %+v", b)
+	}
+}
+
+func TestReconcileSuiteMaxParallel(t *testing.T) {
+	suite := &v1a1.ApacheBenchSuite{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "benchmark", Generation: 1},
+		Spec: v1a1.ApacheBenchSuiteSpec{
+			Matrix:   v1a1.ApacheBenchSuiteMatrixSpec{Concurrency: []uint32{1, 10, 50}},
+			Template: v1a1.ApacheBenchSpec{Requests: 100, URL: "http://example.com/"},
+		},
+	}
+	r := newTestReconciler(t, suite)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: suite.Namespace, Name: suite.Name}}
+
+	// reconcileStatus will reconcile the suite and return its status.
+	reconcileStatus := func() v1a1.ApacheBenchSuiteStatus {
+		t.Helper()
+		if _, err := r.Reconcile(req); err != nil {
+			t.Fatalf("Reconcile returned an error: %v", err)
+		}
+		got := &v1a1.ApacheBenchSuite{}
+		if err := r.client.Get(context.TODO(), req.NamespacedName, got); err != nil {
+			t.Fatal(err)
+		}
+		return got.Status
+	}
+
+	// setRun will record a run for the suite on the ApacheBench with the given name, for the run ID it was started with.
+	setRun := func(name string, phase string) {
+		t.Helper()
+		ab := &v1a1.ApacheBench{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: suite.Namespace, Name: name}, ab); err != nil {
+			t.Fatal(err)
+		}
+		ab.Status.Runs = []v1a1.ApacheBenchRun{{Number: 1, Phase: phase, RunID: ab.Annotations[v1a1.ApacheBenchRunIDAnnotation]}}
+		if err := r.client.Status().Update(context.TODO(), ab); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// getRunID will return the run ID that the ApacheBench with the given name was last started with, if any.
+	getRunID := func(name string) string {
+		t.Helper()
+		ab := &v1a1.ApacheBench{}
+		if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: suite.Namespace, Name: name}, ab); err != nil {
+			return ""
+		}
+		return ab.Annotations[v1a1.ApacheBenchRunIDAnnotation]
+	}
+
+	reconcileStatus()
+	setRun("web-1", v1a1.ApacheBenchPhaseComplete)
+	status := reconcileStatus()
+	if status.Running != 1 || getRunID("web-2") != "1" || len(getRunID("web-3")) > 0 {
+		t.Fatalf("expected only the second benchmark to run, got %+v", status)
+	}
+	setRun("web-2", v1a1.ApacheBenchPhaseRunning)
+
+	// The suite spec changes while the second benchmark is still running its previous run, which counts toward the
+	// maximum, so no benchmark is started.
+	if err := r.client.Get(context.TODO(), req.NamespacedName, suite); err != nil {
+		t.Fatal(err)
+	}
+	suite.Generation = 2
+	suite.Spec.Template.Requests = 200
+	if err := r.client.Update(context.TODO(), suite); err != nil {
+		t.Fatal(err)
+	}
+	status = reconcileStatus()
+	if status.Running != 1 || status.Phase != v1a1.ApacheBenchPhaseRunning {
+		t.Errorf("expected the previous run to be counted as running, got %+v", status)
+	}
+	for _, name := range []string{"web-1", "web-2"} {
+		if got := getRunID(name); got != "1" {
+			t.Errorf("expected %s to wait for the previous run to finish, got run ID %q", name, got)
+		}
+	}
+
+	// Once the previous run finishes, the first benchmark is started with the new spec.
+	setRun("web-2", v1a1.ApacheBenchPhaseComplete)
+	status = reconcileStatus()
+	if status.Running != 1 || getRunID("web-1") != "2" || getRunID("web-2") != "1" {
+		t.Errorf("expected only the first benchmark to run again, got %+v", status)
+	}
+}